@@ -0,0 +1,56 @@
+// Command record captures transactions from RPC as regression fixtures.
+//
+//	go run ./cmd/record [-rpc URL] [-dir DIR] [-file sigs.txt] [signature ...]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/franco-bianco/solanaswap-go/solanaswap-go/fixture"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func main() {
+	rpcURL := flag.String("rpc", os.Getenv("SOLANA_RPC_URL"), "RPC endpoint (default mainnet-beta)")
+	dir := flag.String("dir", "solanaswap-go/testdata", "fixture directory")
+	file := flag.String("file", "", "file with one signature per line")
+	overwrite := flag.Bool("overwrite-golden", false, "replace existing golden files")
+	flag.Parse()
+
+	sigs := flag.Args()
+	if *file != "" {
+		fromFile, err := fixture.ReadSignatures(*file)
+		if err != nil {
+			log.Fatalf("error reading signatures: %s", err)
+		}
+		sigs = append(sigs, fromFile...)
+	}
+	if len(sigs) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *rpcURL == "" {
+		*rpcURL = rpc.MainNetBeta.RPC
+	}
+	recorder := fixture.NewRecorder(rpc.New(*rpcURL), *dir)
+	recorder.OverwriteGolden = *overwrite
+
+	failed := 0
+	for _, sig := range sigs {
+		golden, err := recorder.Record(context.TODO(), sig)
+		if err != nil {
+			log.Printf("error recording %s: %s", sig, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %d legs\n", sig, len(golden.Legs))
+	}
+	if failed > 0 {
+		log.Fatalf("%d of %d signatures failed", failed, len(sigs))
+	}
+}
@@ -8,7 +8,6 @@ require (
 	github.com/gagliardetto/solana-go v1.13.0
 	github.com/mr-tron/base58 v1.2.0
 	github.com/shopspring/decimal v1.3.1
-	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.8.1
 )
 
@@ -0,0 +1,303 @@
+package solanaswapgo
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+//go:embed amm_descriptors.json
+var defaultAMMDescriptors []byte
+
+// AMMAccounts holds instruction account positions of the pool and its two
+// vaults. Negative positions count from the end of the account list.
+type AMMAccounts struct {
+	Pool    int `json:"pool"`
+	PoolIn  int `json:"poolIn"`
+	PoolOut int `json:"poolOut"`
+}
+
+// AMMLamportVault is an instruction account position holding native SOL as
+// lamports instead of a token balance, such as a bonding curve. Its reserve
+// is its lamport balance minus the rent-exempt minimum of its Space bytes of
+// data.
+type AMMLamportVault struct {
+	Account int    `json:"account"`
+	Space   uint64 `json:"space"`
+}
+
+// AMMAccountVariant overrides the account layout for instructions that carry
+// exactly AccountCount accounts.
+type AMMAccountVariant struct {
+	AccountCount int         `json:"accountCount"`
+	Accounts     AMMAccounts `json:"accounts"`
+}
+
+// AMMInstruction overrides the tx type and account layout of an instruction,
+// matched either by its exact discriminator (hex) or by the shared
+// add/remove liquidity discriminator families. Exact matches win, and an
+// exact discriminator is accepted even when it is not one of Discriminators.
+type AMMInstruction struct {
+	Discriminator string       `json:"discriminator,omitempty"`
+	Family        string       `json:"family,omitempty"`
+	Type          string       `json:"type"`
+	Accounts      *AMMAccounts `json:"accounts,omitempty"`
+
+	// MintAccounts are the positions of the pair mints. When set and the
+	// output amount is unknown, the output mint is taken from the pair.
+	MintAccounts []int `json:"mintAccounts,omitempty"`
+
+	// Args is where a swap instruction takes its amounts, see AMMSwapArgs.
+	Args *AMMSwapArgs `json:"args,omitempty"`
+}
+
+// AMMSwapArgs is the layout of the arguments of a swap instruction, used to
+// decode the swap a failed transaction meant to make. Without it the first
+// two u64 are the input amount and the minimum output, and the input is the
+// mint of the first token account of the trader in the instruction.
+type AMMSwapArgs struct {
+	// Offset is where the two u64 start after the discriminator.
+	Offset int `json:"offset,omitempty"`
+	// ExactOut is set when the instruction asks for an output amount for
+	// at most an input amount, LimitFirst when that limit comes first.
+	ExactOut   bool `json:"exactOut,omitempty"`
+	LimitFirst bool `json:"limitFirst,omitempty"`
+	// Input is the position of the token account the trader pays from, or
+	// of the trader when it pays native SOL.
+	Input *int `json:"input,omitempty"`
+}
+
+// AMMDescriptor describes where the pool and vault accounts of an AMM
+// program live so a parsed swap leg can be enriched with pool info.
+type AMMDescriptor struct {
+	ProgramID        solana.PublicKey    `json:"programId"`
+	Protocol         string              `json:"protocol"`
+	DiscriminatorLen int                 `json:"discriminatorLen,omitempty"`
+	Discriminators   []string            `json:"discriminators,omitempty"`
+	Accounts         AMMAccounts         `json:"accounts"`
+	AccountVariants  []AMMAccountVariant `json:"accountVariants,omitempty"`
+	Instructions     []AMMInstruction    `json:"instructions,omitempty"`
+	// LamportVaults are the vaults that are read from lamport balances
+	// when they have no token balance. Other vaults without one fail with
+	// ErrMissingPostBalance.
+	LamportVaults []AMMLamportVault `json:"lamportVaults,omitempty"`
+}
+
+// lamportVault returns the data size of the lamport vault at position idx
+// of an instruction with accountCount accounts.
+func (d *AMMDescriptor) lamportVault(idx, accountCount int) (uint64, bool) {
+	for _, v := range d.LamportVaults {
+		pos := v.Account
+		if pos < 0 {
+			pos += accountCount
+		}
+		if pos == idx {
+			return v.Space, true
+		}
+	}
+	return 0, false
+}
+
+// ammLayout is the outcome of matching an instruction against a descriptor.
+type ammLayout struct {
+	Type         string
+	Accounts     AMMAccounts
+	MintAccounts []int
+	Args         *AMMSwapArgs
+}
+
+func (d *AMMDescriptor) normalize() error {
+	if d.ProgramID.IsZero() {
+		return fmt.Errorf("amm descriptor %q: missing programId", d.Protocol)
+	}
+	if d.Protocol == "" {
+		return fmt.Errorf("amm descriptor %s: missing protocol", d.ProgramID)
+	}
+	if d.DiscriminatorLen == 0 {
+		d.DiscriminatorLen = 8
+	}
+	for i, disc := range d.Discriminators {
+		if err := d.checkDiscriminator(disc); err != nil {
+			return err
+		}
+		d.Discriminators[i] = strings.ToLower(disc)
+	}
+	for i := range d.Instructions {
+		ix := &d.Instructions[i]
+		switch {
+		case ix.Discriminator != "":
+			if err := d.checkDiscriminator(ix.Discriminator); err != nil {
+				return err
+			}
+			ix.Discriminator = strings.ToLower(ix.Discriminator)
+		case ix.Family == TxTypeAdd || ix.Family == TxTypeRemove:
+		default:
+			return fmt.Errorf("amm descriptor %s: instruction needs a discriminator or an add/remove family", d.ProgramID)
+		}
+		if ix.Type == "" {
+			ix.Type = TxTypeSwap
+		}
+	}
+	return nil
+}
+
+func (d *AMMDescriptor) checkDiscriminator(disc string) error {
+	raw, err := hex.DecodeString(disc)
+	if err != nil || len(raw) != d.DiscriminatorLen {
+		return fmt.Errorf("amm descriptor %s: invalid discriminator %q for length %d", d.ProgramID, disc, d.DiscriminatorLen)
+	}
+	return nil
+}
+
+// instructionKinds classifies the hex anchor discriminators shared by many
+// AMMs as swaps or liquidity changes. The maps are never written to, options
+// replace them with copies.
+type instructionKinds struct {
+	swap, add, remove map[string]bool
+}
+
+// defaultInstructionKinds returns a copy of the built-in discriminators.
+func defaultInstructionKinds() instructionKinds {
+	return instructionKinds{
+		swap:   maps.Clone(swapDiscriminator),
+		add:    maps.Clone(addDiscriminator),
+		remove: maps.Clone(removeDiscriminator),
+	}
+}
+
+// match returns the layout of an instruction, or an error when the
+// discriminator is not one the program is known to swap or move liquidity with.
+func (d *AMMDescriptor) match(data []byte, accountCount int, kinds instructionKinds) (*ammLayout, error) {
+	if len(data) < d.DiscriminatorLen {
+		return nil, fmt.Errorf("%w: %d bytes, discriminator needs %d", ErrInvalidInstructionData, len(data), d.DiscriminatorLen)
+	}
+	discriminator := hex.EncodeToString(data[:d.DiscriminatorLen])
+
+	accepted := kinds.add[discriminator] || kinds.remove[discriminator]
+	for _, ix := range d.Instructions {
+		accepted = accepted || ix.Discriminator == discriminator
+	}
+	if len(d.Discriminators) > 0 {
+		for _, disc := range d.Discriminators {
+			accepted = accepted || disc == discriminator
+		}
+	} else {
+		accepted = accepted || kinds.swap[discriminator]
+	}
+	if !accepted {
+		return nil, fmt.Errorf("%w: %s", ErrDiscriminatorUnmatched, discriminator)
+	}
+
+	layout := &ammLayout{Type: TxTypeSwap, Accounts: d.Accounts}
+	for _, v := range d.AccountVariants {
+		if v.AccountCount == accountCount {
+			layout.Accounts = v.Accounts
+			break
+		}
+	}
+
+	var variant *AMMInstruction
+	for i := range d.Instructions {
+		ix := &d.Instructions[i]
+		if ix.Discriminator == discriminator {
+			variant = ix
+			break
+		}
+		if variant == nil && ix.Discriminator == "" &&
+			(ix.Family == TxTypeAdd && kinds.add[discriminator] ||
+				ix.Family == TxTypeRemove && kinds.remove[discriminator]) {
+			variant = ix
+		}
+	}
+	if variant != nil {
+		layout.Type = variant.Type
+		if variant.Accounts != nil {
+			layout.Accounts = *variant.Accounts
+		}
+		layout.MintAccounts = variant.MintAccounts
+		layout.Args = variant.Args
+	}
+	return layout, nil
+}
+
+// AMMRegistry maps AMM program IDs to their descriptors.
+type AMMRegistry struct {
+	mu          sync.RWMutex
+	descriptors map[solana.PublicKey]*AMMDescriptor
+}
+
+func NewAMMRegistry() *AMMRegistry {
+	return &AMMRegistry{descriptors: make(map[solana.PublicKey]*AMMDescriptor)}
+}
+
+// Register adds a descriptor, replacing any existing one for the program.
+func (r *AMMRegistry) Register(d AMMDescriptor) error {
+	d.Discriminators = append([]string(nil), d.Discriminators...)
+	d.Instructions = append([]AMMInstruction(nil), d.Instructions...)
+	d.LamportVaults = append([]AMMLamportVault(nil), d.LamportVaults...)
+	if err := d.normalize(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.descriptors[d.ProgramID] = &d
+	return nil
+}
+
+// Load registers every descriptor of a JSON array.
+func (r *AMMRegistry) Load(raw []byte) error {
+	var descriptors []AMMDescriptor
+	if err := json.Unmarshal(raw, &descriptors); err != nil {
+		return fmt.Errorf("failed to decode amm descriptors: %w", err)
+	}
+	for _, d := range descriptors {
+		if err := r.Register(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile registers every descriptor of a JSON file.
+func (r *AMMRegistry) LoadFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.Load(raw)
+}
+
+func (r *AMMRegistry) Lookup(progID solana.PublicKey) (*AMMDescriptor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.descriptors[progID]
+	return d, ok
+}
+
+// DefaultAMMRegistry is loaded from the embedded amm_descriptors.json and is
+// used by every Parser unless WithRegistry is given.
+var DefaultAMMRegistry = func() *AMMRegistry {
+	r := NewAMMRegistry()
+	if err := r.Load(defaultAMMDescriptors); err != nil {
+		panic(err)
+	}
+	return r
+}()
+
+// RegisterAMM adds or replaces a descriptor in DefaultAMMRegistry.
+func RegisterAMM(d AMMDescriptor) error {
+	return DefaultAMMRegistry.Register(d)
+}
+
+// LoadAMMDescriptors adds or replaces descriptors in DefaultAMMRegistry from
+// a JSON file.
+func LoadAMMDescriptors(path string) error {
+	return DefaultAMMRegistry.LoadFile(path)
+}
@@ -0,0 +1,76 @@
+package solanaswapgo
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	raw, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestAMMDescriptor_Match(t *testing.T) {
+	raydiumV4, ok := DefaultAMMRegistry.Lookup(RAYDIUM_V4_PROGRAM_ID)
+	require.True(t, ok)
+	orca, ok := DefaultAMMRegistry.Lookup(ORCA_PROGRAM_ID)
+	require.True(t, ok)
+	cpmm, ok := DefaultAMMRegistry.Lookup(RAYDIUM_CPMM_PROGRAM_ID)
+	require.True(t, ok)
+
+	tests := []struct {
+		name       string
+		descriptor *AMMDescriptor
+		data       []byte
+		accounts   int
+		want       ammLayout
+	}{
+		{"raydium v4 swap", raydiumV4, []byte{9}, 17, ammLayout{Type: TxTypeSwap, Accounts: AMMAccounts{1, 4, 5}}},
+		{"raydium v4 swap with 18 accounts", raydiumV4, []byte{9}, 18, ammLayout{Type: TxTypeSwap, Accounts: AMMAccounts{1, 5, 6}}},
+		{"raydium v4 swap base out", raydiumV4, []byte{11}, 18, ammLayout{Type: TxTypeSwap, Accounts: AMMAccounts{1, 5, 6}, Args: &AMMSwapArgs{ExactOut: true, LimitFirst: true}}},
+		{"raydium v4 deposit", raydiumV4, []byte{3}, 18, ammLayout{Type: TxTypeAdd, Accounts: AMMAccounts{1, 6, 7}}},
+		{"orca swap", orca, mustHex(t, calculateDiscriminator("global:swap")), 11, ammLayout{Type: TxTypeSwap, Accounts: AMMAccounts{2, 4, 6}}},
+		{"orca decrease liquidity", orca, mustHex(t, calculateDiscriminator("global:decrease_liquidity")), 11, ammLayout{Type: TxTypeRemove, Accounts: AMMAccounts{0, -4, -3}}},
+		{"cpmm deposit", cpmm, mustHex(t, calculateDiscriminator("global:deposit")), 13, ammLayout{Type: TxTypeAdd, Accounts: AMMAccounts{2, 6, 7}}},
+		{"cpmm initialize", cpmm, mustHex(t, calculateDiscriminator("global:initialize")), 20, ammLayout{Type: TxTypeAdd, Accounts: AMMAccounts{3, 10, 11}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.descriptor.match(tt.data, tt.accounts, defaultInstructionKinds())
+			require.NoError(t, err)
+			require.Equal(t, tt.want, *got)
+		})
+	}
+
+	_, err := raydiumV4.match([]byte{2}, 17, defaultInstructionKinds())
+	require.Error(t, err)
+	_, err = orca.match([]byte{1, 2, 3}, 11, defaultInstructionKinds())
+	require.Error(t, err)
+}
+
+func TestAMMRegistry_Load(t *testing.T) {
+	r := NewAMMRegistry()
+	require.NoError(t, r.Load(defaultAMMDescriptors))
+	require.NoError(t, r.Load([]byte(`[{
+		"programId": "9H6tua7jkLhdm3w8BvgpTn5LZNU7g4ZynDmCiNN3q6Rp",
+		"protocol": "HumidiFi",
+		"discriminatorLen": 1,
+		"discriminators": ["2A"],
+		"accounts": {"pool": 0, "poolIn": 1, "poolOut": 2}
+	}]`)))
+
+	d, ok := r.Lookup(HUMIDIDI_PROGRAM_ID)
+	require.True(t, ok)
+	layout, err := d.match([]byte{0x2a, 0xff}, 3, defaultInstructionKinds())
+	require.NoError(t, err)
+	require.Equal(t, AMMAccounts{0, 1, 2}, layout.Accounts)
+
+	// the default registry is left untouched
+	d, _ = DefaultAMMRegistry.Lookup(HUMIDIDI_PROGRAM_ID)
+	require.Equal(t, 8, d.DiscriminatorLen)
+
+	require.Error(t, r.Load([]byte(`[{"programId": "9H6tua7jkLhdm3w8BvgpTn5LZNU7g4ZynDmCiNN3q6Rp", "protocol": "x", "discriminators": ["01"]}]`)))
+}
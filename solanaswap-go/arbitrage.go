@@ -0,0 +1,64 @@
+package solanaswapgo
+
+import "github.com/gagliardetto/solana-go"
+
+// ArbitrageInfo is a route that starts and ends in the same asset, such as
+// SOL→A→B→SOL.
+type ArbitrageInfo struct {
+	Trader   solana.PublicKey
+	Mint     solana.PublicKey
+	Decimals uint8
+	Route    *Route
+
+	InputAmount  uint64
+	OutputAmount uint64
+	// GrossProfit is OutputAmount minus InputAmount in Mint.
+	GrossProfit int64
+	Fees        Fees
+	// NetProfit is GrossProfit minus the fees when Mint is SOL. The fees
+	// of a cycle in another asset cannot be converted without a price, its
+	// NetProfit is GrossProfit.
+	NetProfit int64
+}
+
+// Cyclic reports whether the route goes through several hops back to the
+// mint it started from.
+func (r *Route) Cyclic() bool {
+	return len(r.Hops) > 1 && r.InputMint.Equals(r.OutputMint)
+}
+
+// arbitrage returns the arbitrage of a cyclic route, nil for other routes.
+func (p *Parser) arbitrage(route *Route, trader solana.PublicKey) *ArbitrageInfo {
+	if route == nil || !route.Cyclic() {
+		return nil
+	}
+	arb := &ArbitrageInfo{
+		Trader:       trader,
+		Mint:         route.InputMint,
+		Decimals:     route.InputDecimals,
+		Route:        route,
+		InputAmount:  route.InputAmount,
+		OutputAmount: route.OutputAmount,
+		GrossProfit:  int64(route.OutputAmount) - int64(route.InputAmount),
+		Fees:         p.Fees(),
+	}
+	arb.NetProfit = arb.GrossProfit
+	if arb.Mint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) {
+		arb.NetProfit -= int64(arb.Fees.Total())
+	}
+	return arb
+}
+
+// ProcessArbitrage classifies the legs as an arbitrage, ErrNoArbitrage when
+// their route is not a cycle.
+func (p *Parser) ProcessArbitrage(swapDatas []SwapData) (*ArbitrageInfo, error) {
+	route, err := p.buildRoute(swapDatas)
+	if err != nil {
+		return nil, err
+	}
+	arb := p.arbitrage(route, p.owner())
+	if arb == nil {
+		return nil, ErrNoArbitrage
+	}
+	return arb, nil
+}
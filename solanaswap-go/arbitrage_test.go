@@ -0,0 +1,96 @@
+package solanaswapgo_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+func computeUnitLimitData(limit uint32) []byte {
+	return binary.LittleEndian.AppendUint32([]byte{2}, limit)
+}
+
+func computeUnitPriceData(microLamports uint64) []byte {
+	return binary.LittleEndian.AppendUint64([]byte{3}, microLamports)
+}
+
+// buildArbitrage runs SOL→A→SOL through Jupiter with a priority fee and a
+// Jito tip.
+func buildArbitrage(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "arbitrage")
+	mintA := b.account("mint-a")
+	sol := solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID
+	tip := solanaswapgo.JitoTipAccounts[0]
+
+	b.addOuter(solana.ComputeBudget, nil, computeUnitLimitData(300_000))
+	b.addOuter(solana.ComputeBudget, nil, computeUnitPriceData(100_000))
+	ix := b.addOuter(solanaswapgo.JUPITER_PROGRAM_ID, []solana.PublicKey{b.signer}, append(anchorDiscriminator("route"), make([]byte, 16)...))
+	b.addInner(ix, 2, solanaswapgo.JUPITER_PROGRAM_ID, nil, jupiterRouteEventData(orcaWhirlpool, sol, 1_000_000_000, mintA, 5_000_000))
+	b.addInner(ix, 2, solanaswapgo.JUPITER_PROGRAM_ID, nil, jupiterRouteEventData(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, mintA, 5_000_000, sol, 1_010_000_000))
+	b.addOuter(solana.SystemProgramID, []solana.PublicKey{b.signer, tip}, systemTransferData(1_000_000))
+
+	b.meta.Fee = 5000 + 30_000
+	b.lamports(b.signer, 2_000_000_000, 2_000_000_000+10_000_000-35_000-1_000_000)
+	b.lamports(tip, 0, 1_000_000)
+	return b
+}
+
+func TestArbitrage(t *testing.T) {
+	b := buildArbitrage(t)
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 2)
+
+	fees := parser.Fees()
+	require.Equal(t, solanaswapgo.Fees{
+		Network:          5000,
+		Priority:         30_000,
+		Tip:              1_000_000,
+		ComputeUnitLimit: 300_000,
+		ComputeUnitPrice: 100_000,
+	}, fees)
+	require.Equal(t, int64(10_000_000), parser.NativeSOLChange(b.signer))
+
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	arb := info.Arbitrage
+	require.NotNil(t, arb)
+	require.Equal(t, b.signer, arb.Trader)
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, arb.Mint)
+	require.Equal(t, int64(10_000_000), arb.GrossProfit)
+	require.Equal(t, int64(10_000_000-1_035_000), arb.NetProfit)
+	require.Equal(t, fees, arb.Fees)
+
+	processed, err := parser.ProcessArbitrage(legs)
+	require.NoError(t, err)
+	require.Equal(t, arb, processed)
+
+	parser, err = solanaswapgo.NewParserWithConfig(buildRaydiumCPMMSwap(t).result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err = parser.ParseTransaction()
+	require.NoError(t, err)
+	_, err = parser.ProcessArbitrage(legs)
+	require.ErrorIs(t, err, solanaswapgo.ErrNoArbitrage)
+	info, _, err = parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Nil(t, info.Arbitrage)
+}
+
+func TestFeesDefaultComputeUnitLimit(t *testing.T) {
+	b := buildRaydiumCPMMSwap(t)
+	b.addOuter(solana.ComputeBudget, nil, computeUnitPriceData(1_000_000))
+	b.meta.Fee = 5000 + 200_000
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+
+	fees := parser.Fees()
+	require.Equal(t, uint32(200_000), fees.ComputeUnitLimit)
+	require.Equal(t, uint64(200_000), fees.Priority)
+	require.Equal(t, uint64(5000), fees.Network)
+	require.Zero(t, fees.Tip)
+}
@@ -0,0 +1,148 @@
+package solanaswapgo
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// BalanceDelta is the net change of one mint held by one owner over the
+// transaction. Native SOL is counted together with WSOL under
+// NATIVE_SOL_MINT_PROGRAM_ID, without the transaction fee and rent.
+type BalanceDelta struct {
+	Owner    solana.PublicKey
+	Mint     solana.PublicKey
+	Decimals uint8
+	Amount   int64
+}
+
+type ownerMint struct {
+	owner solana.PublicKey
+	mint  solana.PublicKey
+}
+
+// BalanceDeltas returns the non-zero changes of every owner of a token
+// account and of the signers, sorted by owner and mint.
+func (p *Parser) BalanceDeltas() []BalanceDelta {
+	p.computeBalanceDeltas()
+	return append([]BalanceDelta(nil), p.deltas...)
+}
+
+// computeBalanceDeltas fills the deltas of the parser the first time it is
+// called, the balances do not change afterwards.
+func (p *Parser) computeBalanceDeltas() {
+	if p.deltaIndex != nil {
+		return
+	}
+	deltas := make(map[ownerMint]*BalanceDelta)
+	add := func(balances []rpc.TokenBalance, sign int64) {
+		for _, balance := range balances {
+			if balance.Owner == nil || balance.UiTokenAmount == nil || balance.Mint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) {
+				continue
+			}
+			amount, err := strconv.ParseInt(balance.UiTokenAmount.Amount, 10, 64)
+			if err != nil {
+				continue
+			}
+			key := ownerMint{*balance.Owner, balance.Mint}
+			if deltas[key] == nil {
+				deltas[key] = &BalanceDelta{Owner: *balance.Owner, Mint: balance.Mint, Decimals: balance.UiTokenAmount.Decimals}
+			}
+			deltas[key].Amount += sign * amount
+		}
+	}
+	add(p.txMeta.PostTokenBalances, 1)
+	add(p.txMeta.PreTokenBalances, -1)
+
+	owners := make(map[solana.PublicKey]bool)
+	for _, balances := range [][]rpc.TokenBalance{p.txMeta.PreTokenBalances, p.txMeta.PostTokenBalances} {
+		for _, balance := range balances {
+			if balance.Owner != nil {
+				owners[*balance.Owner] = true
+			}
+		}
+	}
+	for _, signer := range p.txInfo.Message.Signers() {
+		owners[signer] = true
+	}
+	for owner := range owners {
+		deltas[ownerMint{owner, NATIVE_SOL_MINT_PROGRAM_ID}] = &BalanceDelta{
+			Owner:    owner,
+			Mint:     NATIVE_SOL_MINT_PROGRAM_ID,
+			Decimals: 9,
+			Amount:   p.NativeSOLChange(owner),
+		}
+	}
+
+	var out []BalanceDelta
+	for _, delta := range deltas {
+		if delta.Amount != 0 {
+			out = append(out, *delta)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if c := out[i].Owner.String(); c != out[j].Owner.String() {
+			return c < out[j].Owner.String()
+		}
+		return out[i].Mint.String() < out[j].Mint.String()
+	})
+	p.deltas = out
+	p.deltaIndex = make(map[ownerMint]int, len(out))
+	for i, delta := range out {
+		p.deltaIndex[ownerMint{delta.Owner, delta.Mint}] = i
+	}
+}
+
+// balanceDelta returns the change of mint held by owner.
+func (p *Parser) balanceDelta(owner, mint solana.PublicKey) (BalanceDelta, bool) {
+	p.computeBalanceDeltas()
+	i, ok := p.deltaIndex[ownerMint{owner, mint}]
+	if !ok {
+		return BalanceDelta{}, false
+	}
+	return p.deltas[i], true
+}
+
+// inferSwap builds a leg from the balance changes of the owner when exactly
+// one asset went down and another went up. It is used when no handler
+// parsed a swap.
+func (p *Parser) inferSwap() []SwapData {
+	owner := p.owner()
+	p.computeBalanceDeltas()
+	var in, out []BalanceDelta
+	for _, delta := range p.deltas {
+		switch {
+		case !delta.Owner.Equals(owner):
+		case delta.Amount < 0:
+			in = append(in, delta)
+		default:
+			out = append(out, delta)
+		}
+	}
+	if len(in) != 1 || len(out) != 1 {
+		return nil
+	}
+
+	tx := &TxInfo{
+		Type:               TxTypeSwap,
+		InputMint:          in[0].Mint,
+		InputAmount:        uint64(-in[0].Amount),
+		InputMintDecimals:  in[0].Decimals,
+		OutputMint:         out[0].Mint,
+		OutputAmount:       uint64(out[0].Amount),
+		OutputMintDecimals: out[0].Decimals,
+		Owner:              owner,
+		Protocol:           string(INFERRED),
+		Method:             MethodInferred,
+	}
+	for i, instr := range p.txInfo.Message.Instructions {
+		if progID := p.allAccountKeys[instr.ProgramIDIndex]; !isInfrastructureProgram(progID) {
+			tx.Router = progID
+			tx.setPosition(i, -1)
+			break
+		}
+	}
+	return []SwapData{{Type: INFERRED, Tx: tx, outer: tx.OuterIndex}}
+}
@@ -0,0 +1,56 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferredSwap(t *testing.T) {
+	// the curve program of buildBondingCurveBuy has no handler, nothing is
+	// inferred unless asked for
+	b, program := buildBondingCurveBuy(t)
+	legs, err := parseWithConfig(t, b, solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Empty(t, legs)
+
+	inference := solanaswapgo.NewParserConfig(solanaswapgo.WithInference(true))
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), inference)
+	require.NoError(t, err)
+	legs, err = parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+
+	require.Equal(t, solanaswapgo.INFERRED, legs[0].Type)
+	tx := legs[0].Tx
+	require.Equal(t, solanaswapgo.MethodInferred, tx.Method)
+	require.Equal(t, program, tx.Router)
+	require.Equal(t, b.signer, tx.Owner)
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, tx.InputMint)
+	require.Equal(t, uint64(1_000_000_000), tx.InputAmount) // without fee and rent
+	require.Equal(t, uint8(9), tx.InputMintDecimals)
+	require.Equal(t, syntheticMint, tx.OutputMint)
+	require.Equal(t, uint64(3_000_000_000_000), tx.OutputAmount)
+	require.Equal(t, uint8(6), tx.OutputMintDecimals)
+
+	curve := b.account("curve")
+	deltas := parser.BalanceDeltas()
+	require.Contains(t, deltas, solanaswapgo.BalanceDelta{Owner: curve, Mint: syntheticMint, Decimals: 6, Amount: -3_000_000_000_000})
+	// the deltas are computed once, callers get their own copy
+	deltas[0].Amount = 0
+	require.Equal(t, parser.BalanceDeltas()[1:], deltas[1:])
+	require.NotEqual(t, parser.BalanceDeltas()[0], deltas[0])
+
+	// a second asset going up is not a swap the trader can be credited with
+	b, _ = buildBondingCurveBuy(t)
+	b.tokenAccount(b.account("user-usdc"), syntheticUSDC, b.signer, 6, 0, 1_000_000)
+	legs, err = parseWithConfig(t, b, inference)
+	require.NoError(t, err)
+	require.Empty(t, legs)
+
+	legs, err = parseWithConfig(t, buildRaydiumCPMMSwap(t), inference)
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, solanaswapgo.MethodParsed, legs[0].Tx.Method)
+}
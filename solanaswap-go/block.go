@@ -0,0 +1,158 @@
+package solanaswapgo
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// BlockTransaction is a transaction placed in its block.
+type BlockTransaction struct {
+	Slot      uint64
+	BlockTime time.Time
+	// TxIndex is the position of the transaction in its block.
+	TxIndex     int
+	Transaction *solana.Transaction
+	Meta        *rpc.TransactionMeta
+
+	// err is why the transaction could not be decoded from its block.
+	err error
+}
+
+// BlockResult is the outcome of parsing one transaction of a block.
+type BlockResult struct {
+	Slot uint64
+	// BlockTime is zero when the node did not report it.
+	BlockTime time.Time
+	TxIndex   int
+	Signature solana.Signature
+	Status    TxStatus
+	Swaps     []SwapData
+	// Parser parsed the transaction, use it to process the swaps.
+	Parser *Parser
+	Err    error
+}
+
+// ParseBlock parses the transactions of a getBlock result fetched with full
+// transaction details. The result does not carry its slot, pass the one it
+// was requested for. A transaction that cannot be decoded gets a result
+// with its Err, the others are still parsed.
+func ParseBlock(slot uint64, block *rpc.GetBlockResult, config *ParserConfig) ([]BlockResult, error) {
+	if block == nil {
+		return nil, fmt.Errorf("block is missing")
+	}
+	var blockTime time.Time
+	if block.BlockTime != nil {
+		blockTime = block.BlockTime.Time()
+	}
+
+	txs := make([]BlockTransaction, 0, len(block.Transactions))
+	for i, twm := range block.Transactions {
+		if twm.Transaction == nil {
+			continue
+		}
+		tx, err := twm.GetTransaction()
+		if err != nil {
+			err = fmt.Errorf("failed to get transaction %d: %w", i, err)
+		}
+		txs = append(txs, BlockTransaction{Slot: slot, BlockTime: blockTime, TxIndex: i, Transaction: tx, Meta: twm.Meta, err: err})
+	}
+	return ParseTransactions(txs, config), nil
+}
+
+// ParseTransactions parses the transactions with a pool of workers, see
+// WithWorkers. Votes and transactions that only call infrastructure programs
+// such as System and Token are skipped. The results are in the order of the
+// transactions.
+func ParseTransactions(txs []BlockTransaction, config *ParserConfig) []BlockResult {
+	if config == nil {
+		config = NewParserConfig()
+	}
+	workers := config.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	results := make([]*BlockResult, len(txs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(txs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = parseBlockTransaction(txs[i], config)
+			}
+		}()
+	}
+	for i := range txs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var parsed []BlockResult
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if result.Parser != nil && result.Parser.deferValues {
+			result.Parser.deferValues = false
+			if result.Err == nil {
+				result.Parser.valueLegs(result.Swaps)
+			}
+		}
+		parsed = append(parsed, *result)
+	}
+	return parsed
+}
+
+func parseBlockTransaction(tx BlockTransaction, config *ParserConfig) *BlockResult {
+	if tx.err != nil {
+		return &BlockResult{Slot: tx.Slot, BlockTime: tx.BlockTime, TxIndex: tx.TxIndex, Err: tx.err}
+	}
+	if !isSwapCandidate(tx.Transaction) {
+		return nil
+	}
+	result := &BlockResult{Slot: tx.Slot, BlockTime: tx.BlockTime, TxIndex: tx.TxIndex}
+	if len(tx.Transaction.Signatures) > 0 {
+		result.Signature = tx.Transaction.Signatures[0]
+	}
+	result.Parser, result.Err = NewTransactionParserWithConfig(tx.Transaction, tx.Meta, config)
+	if result.Err != nil {
+		return result
+	}
+	result.Parser.SetBlockContext(tx.Slot, tx.BlockTime, tx.TxIndex)
+	// an oracle learning from the swaps sees them in block order, whatever
+	// worker parsed them
+	_, result.Parser.deferValues = config.oracle.(swapObserver)
+	result.Status = result.Parser.Status()
+	result.Swaps, result.Err = result.Parser.ParseTransaction()
+	return result
+}
+
+// isSwapCandidate tells votes and transactions that only call
+// infrastructure programs apart from the ones that may swap.
+func isSwapCandidate(tx *solana.Transaction) bool {
+	if tx == nil {
+		return false
+	}
+	for _, instr := range tx.Message.Instructions {
+		if int(instr.ProgramIDIndex) >= len(tx.Message.AccountKeys) {
+			// let the parser report the bad index
+			return true
+		}
+		progID := tx.Message.AccountKeys[instr.ProgramIDIndex]
+		if progID.Equals(solana.VoteProgramID) {
+			return false
+		}
+		if !isInfrastructureProgram(progID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+	"time"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// blockTransaction encodes the transaction of b the way getBlock does.
+func blockTransaction(t *testing.T, b *txBuilder) rpc.TransactionWithMeta {
+	result := b.result()
+	tx, err := result.Transaction.GetTransaction()
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	return rpc.TransactionWithMeta{Transaction: rpc.DataBytesOrJSONFromBytes(raw), Meta: result.Meta}
+}
+
+func TestParseBlock(t *testing.T) {
+	vote := newTxBuilder(t, "vote")
+	vote.addOuter(solana.VoteProgramID, []solana.PublicKey{vote.account("vote-account"), vote.signer}, []byte{12, 0, 0, 0})
+	transfer := newTxBuilder(t, "transfer")
+	transfer.addOuter(solana.SystemProgramID, []solana.PublicKey{transfer.signer, transfer.account("to")}, systemTransferData(1_000))
+
+	builders := []*txBuilder{vote, buildRaydiumCPMMSwap(t), transfer, buildPumpSwapBuy(t), buildRaydiumCPMMSwap(t)}
+	blockTime := solana.UnixTimeSeconds(1_730_000_400)
+	block := &rpc.GetBlockResult{BlockTime: &blockTime}
+	for _, b := range builders {
+		block.Transactions = append(block.Transactions, blockTransaction(t, b))
+	}
+
+	results, err := solanaswapgo.ParseBlock(300_000_001, block, solanaswapgo.NewParserConfig(solanaswapgo.WithWorkers(2)))
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for i, txIndex := range []int{1, 3, 4} {
+		result := results[i]
+		require.NoError(t, result.Err)
+		require.Equal(t, txIndex, result.TxIndex)
+		require.Equal(t, uint64(300_000_001), result.Slot)
+		require.Equal(t, time.Unix(1_730_000_400, 0), result.BlockTime)
+		require.Equal(t, builders[txIndex].signature(), result.Signature.String())
+		require.Len(t, result.Swaps, 1)
+		require.Equal(t, time.Unix(1_730_000_400, 0), result.Swaps[0].Tx.BlockTime)
+		require.NotNil(t, result.Parser)
+	}
+	require.Equal(t, solanaswapgo.PUMP_FUN, results[1].Swaps[0].Type)
+
+	_, err = solanaswapgo.ParseBlock(0, nil, nil)
+	require.Error(t, err)
+}
+
+func TestParseBlock_DecodeError(t *testing.T) {
+	swap := buildRaydiumCPMMSwap(t)
+	block := &rpc.GetBlockResult{Transactions: []rpc.TransactionWithMeta{
+		{Transaction: rpc.DataBytesOrJSONFromBytes([]byte{1, 2, 3})},
+		blockTransaction(t, swap),
+	}}
+
+	results, err := solanaswapgo.ParseBlock(300_000_001, block, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, 0, results[0].TxIndex)
+	require.Error(t, results[0].Err)
+	require.Nil(t, results[0].Parser)
+	require.Equal(t, 1, results[1].TxIndex)
+	require.NoError(t, results[1].Err)
+	require.Equal(t, swap.signature(), results[1].Signature.String())
+	require.Len(t, results[1].Swaps, 1)
+}
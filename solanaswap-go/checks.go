@@ -11,7 +11,7 @@ import (
 func (p *Parser) isTransfer(instr solana.CompiledInstruction) bool {
 	progID := p.allAccountKeys[instr.ProgramIDIndex]
 
-	if !progID.Equals(solana.TokenProgramID) {
+	if !isTokenProgram(progID) {
 		return false
 	}
 
@@ -19,7 +19,7 @@ func (p *Parser) isTransfer(instr solana.CompiledInstruction) bool {
 		return false
 	}
 
-	if instr.Data[0] != 3 {
+	if instr.Data[0] != tokenInstructionTransfer {
 		return false
 	}
 
@@ -32,19 +32,20 @@ func (p *Parser) isTransfer(instr solana.CompiledInstruction) bool {
 	return true
 }
 
-// isTransferCheck checks if the instruction is a token transfer check (Meteora)
+// isTransferCheck checks if the instruction is a token transfer check
+// (Meteora), including the TransferCheckedWithFee of Token-2022
 func (p *Parser) isTransferCheck(instr solana.CompiledInstruction) bool {
 	progID := p.allAccountKeys[instr.ProgramIDIndex]
 
-	if !progID.Equals(solana.TokenProgramID) && !progID.Equals(solana.Token2022ProgramID) {
+	if !isTokenProgram(progID) {
 		return false
 	}
 
-	if len(instr.Accounts) < 4 || len(instr.Data) < 9 {
+	if len(instr.Accounts) < 4 {
 		return false
 	}
 
-	if instr.Data[0] != 12 {
+	if _, ok := decodeCheckedTransfer(instr.Data); !ok {
 		return false
 	}
 
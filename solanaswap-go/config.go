@@ -0,0 +1,200 @@
+package solanaswapgo
+
+import (
+	"context"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ParserConfig holds the settings shared by parsers. Build it once with
+// NewParserConfig and pass it to NewParserWithConfig; it is not modified
+// afterwards and can be shared between goroutines.
+type ParserConfig struct {
+	ctx         context.Context
+	logger      Logger
+	ammRegistry *AMMRegistry
+	handlers    []ProtocolHandler
+	enabled     map[string]bool
+	routers     []solana.PublicKey
+	kinds       instructionKinds
+	mintInfo    MintInfoProvider
+	oracle      PriceOracle
+	quoteAssets []solana.PublicKey
+	strict      bool
+	inference   bool
+	workers     int
+	intents     bool
+
+	protocols []ProtocolHandler
+	// boundaries are all the handlers before WithProtocols, the AMMs among
+	// them end the transfers scanned for a leg.
+	boundaries []ProtocolHandler
+}
+
+// Option configures a ParserConfig.
+type Option func(*ParserConfig)
+
+// NewParserConfig returns a config with the built-in and registered
+// protocols, the built-in router programs and swap instructions,
+// DefaultAMMRegistry and a no-op logger, changed by opts.
+func NewParserConfig(opts ...Option) *ParserConfig {
+	c := &ParserConfig{
+		ctx:         context.Background(),
+		logger:      NopLogger{},
+		ammRegistry: DefaultAMMRegistry,
+		routers:     append([]solana.PublicKey(nil), routerPrograms...),
+		kinds:       defaultInstructionKinds(),
+		quoteAssets: DefaultQuoteAssets,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// the handlers of WithHandlers are always on, WithProtocols picks
+	// among the registered ones
+	c.protocols = append(c.protocols, c.handlers...)
+	c.boundaries = append(c.boundaries, c.handlers...)
+	for _, h := range registeredProtocols() {
+		if _, ok := h.(configRouters); ok {
+			h = routerHandler{programs: c.routers}
+		}
+		c.boundaries = append(c.boundaries, h)
+		if c.enabled == nil || c.enabled[h.Name()] {
+			c.protocols = append(c.protocols, h)
+		}
+	}
+	return c
+}
+
+// WithLogger sets the logger of the parser. Lines are discarded by default.
+func WithLogger(l Logger) Option {
+	return func(c *ParserConfig) {
+		if l != nil {
+			c.logger = l
+		}
+	}
+}
+
+// WithRegistry replaces DefaultAMMRegistry as the source of pool layouts.
+func WithRegistry(r *AMMRegistry) Option {
+	return func(c *ParserConfig) {
+		if r != nil {
+			c.ammRegistry = r
+		}
+	}
+}
+
+// WithHandlers adds protocol handlers in front of the registered ones, like
+// RegisterProtocol but only for parsers using the config.
+func WithHandlers(handlers ...ProtocolHandler) Option {
+	return func(c *ParserConfig) {
+		c.handlers = append(c.handlers, handlers...)
+	}
+}
+
+// WithProtocols enables only the built-in and registered handlers with the
+// given names, e.g. "jupiter", "router", "raydium" or "pumpfun".
+// Instructions of the other protocols are left unparsed. Handlers of
+// WithHandlers stay enabled.
+func WithProtocols(names ...string) Option {
+	return func(c *ParserConfig) {
+		c.enabled = make(map[string]bool, len(names))
+		for _, name := range names {
+			c.enabled[name] = true
+		}
+	}
+}
+
+// WithRouterPrograms adds programs whose swaps are only parsed from their
+// inner instructions, such as trading bots, to the built-in ones. They are
+// parsed by the "router" protocol.
+func WithRouterPrograms(programs ...solana.PublicKey) Option {
+	return func(c *ParserConfig) {
+		c.routers = append(c.routers, programs...)
+	}
+}
+
+// WithSwapInstructions adds anchor instruction names, e.g. "swap_v3", that
+// AMMs without a discriminator list in their descriptor swap with.
+func WithSwapInstructions(names ...string) Option {
+	return func(c *ParserConfig) {
+		swap := make(map[string]bool, len(c.kinds.swap)+len(names))
+		for k, v := range c.kinds.swap {
+			swap[k] = v
+		}
+		for _, name := range names {
+			swap[calculateDiscriminator("global:"+name)] = true
+		}
+		c.kinds.swap = swap
+	}
+}
+
+// WithStrict makes ParseTransaction fail with the recorded errors when any
+// instruction could not be parsed, instead of returning the legs it found.
+func WithStrict(strict bool) Option {
+	return func(c *ParserConfig) {
+		c.strict = strict
+	}
+}
+
+// WithMintInfoProvider sets where the decimals of mints without a token
+// balance in the transaction are looked up. Wrap slow providers such as
+// RPCMintInfo in an LRUMintInfo.
+func WithMintInfoProvider(provider MintInfoProvider) Option {
+	return func(c *ParserConfig) {
+		c.mintInfo = provider
+	}
+}
+
+// WithContext sets the context given to the price oracle and the mint info
+// provider, to cancel or time out their lookups.
+func WithContext(ctx context.Context) Option {
+	return func(c *ParserConfig) {
+		c.ctx = ctx
+	}
+}
+
+// WithPriceOracle values the amounts of every leg and swap in USD with
+// oracle. Oracles that learn from swaps, such as InStreamOracle, see the
+// legs of each transaction before they are valued.
+func WithPriceOracle(oracle PriceOracle) Option {
+	return func(c *ParserConfig) {
+		c.oracle = oracle
+	}
+}
+
+// WithQuoteAssets sets the quote asset priority that tells the base and
+// quote of a swap apart, highest first, see DefaultQuoteAssets. Mints left
+// out rank below all of them.
+func WithQuoteAssets(mints ...solana.PublicKey) Option {
+	return func(c *ParserConfig) {
+		c.quoteAssets = mints
+	}
+}
+
+// WithInference turns on the fallback that infers a swap from the balance
+// changes of the trader when no handler parsed one. It is off by default,
+// so a transaction no handler understands, such as a plain transfer, gives
+// no legs.
+func WithInference(inference bool) Option {
+	return func(c *ParserConfig) {
+		c.inference = inference
+	}
+}
+
+// WithWorkers sets how many transactions ParseBlock and ParseTransactions
+// parse at once. It defaults to GOMAXPROCS.
+func WithWorkers(n int) Option {
+	return func(c *ParserConfig) {
+		c.workers = n
+	}
+}
+
+// WithFailedSwapIntents makes ParseTransaction decode the swaps a failed
+// transaction meant to make from its instruction arguments, as
+// MethodIntended legs. Failed transactions give no legs by default.
+func WithFailedSwapIntents(intents bool) Option {
+	return func(c *ParserConfig) {
+		c.intents = intents
+	}
+}
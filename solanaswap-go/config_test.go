@@ -0,0 +1,113 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+func parseWithConfig(t *testing.T, b *txBuilder, config *solanaswapgo.ParserConfig) ([]solanaswapgo.SwapData, error) {
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), config)
+	require.NoError(t, err)
+	return parser.ParseTransaction()
+}
+
+func TestParserConfig(t *testing.T) {
+	legs, err := parseWithConfig(t, buildRaydiumCPMMSwap(t), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+
+	onlyPumpfun := solanaswapgo.NewParserConfig(solanaswapgo.WithProtocols(solanaswapgo.PROTOCOL_PUMPFUN))
+	legs, err = parseWithConfig(t, buildRaydiumCPMMSwap(t), onlyPumpfun)
+	require.NoError(t, err)
+	require.Empty(t, legs)
+	legs, err = parseWithConfig(t, buildRaydiumCPMMSwap(t), solanaswapgo.NewParserConfig(solanaswapgo.WithProtocols(solanaswapgo.PROTOCOL_PUMPFUN), solanaswapgo.WithInference(true)))
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, solanaswapgo.INFERRED, legs[0].Type)
+	legs, err = parseWithConfig(t, buildPumpSwapBuy(t), onlyPumpfun)
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+
+	unmatched := buildRaydiumCPMMSwap(t)
+	unmatched.outer[0].Data = append(anchorDiscriminator("collect_fund_fee"), make([]byte, 16)...)
+	legs, err = parseWithConfig(t, unmatched, solanaswapgo.NewParserConfig(solanaswapgo.WithStrict(true)))
+	require.NoError(t, err)
+	require.Empty(t, legs)
+
+	legs, err = parseWithConfig(t, unmatched, solanaswapgo.NewParserConfig(solanaswapgo.WithSwapInstructions("collect_fund_fee")))
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+}
+
+func TestWithRouterPrograms(t *testing.T) {
+	b := newTxBuilder(t, "custom-router")
+	router := b.account("router")
+	pool := b.account("pool")
+	authority := b.account("authority")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+
+	ix := b.addOuter(router, []solana.PublicKey{b.signer}, []byte{1})
+	b.addInner(ix, 2, solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userIn, userOut, vaultIn, vaultOut,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(ix, 3, userIn, vaultIn, b.signer, 25_000_000)
+	b.addTransfer(ix, 3, vaultOut, userOut, authority, 61_234_567_890)
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 100_000_000, 75_000_000)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 61_234_567_890)
+	b.tokenAccount(vaultIn, syntheticUSDC, authority, 6, 5_000_000_000, 5_025_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, authority, 6, 12_000_000_000_000, 11_938_765_432_110)
+
+	legs, err := parseWithConfig(t, b, solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Empty(t, legs)
+
+	legs, err = parseWithConfig(t, b, solanaswapgo.NewParserConfig(solanaswapgo.WithRouterPrograms(router)))
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, uint64(25_000_000), legs[0].Tx.InputAmount)
+	require.Equal(t, uint64(61_234_567_890), legs[0].Tx.OutputAmount)
+	require.Equal(t, vaultIn, legs[0].Tx.PoolIn)
+}
+
+func TestWithHandlersAndProtocols(t *testing.T) {
+	b := newTxBuilder(t, "handler-dex")
+	program := b.account("program")
+	pool := b.account("pool")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+	ix := b.addOuter(program, []solana.PublicKey{pool, userIn, userOut, vaultIn, vaultOut, b.signer}, []byte{0x2a})
+	b.addTransfer(ix, 2, userIn, vaultIn, b.signer, 10_000_000)
+	b.addTransfer(ix, 2, vaultOut, userOut, pool, 7_000_000_000)
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 10_000_000, 0)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 7_000_000_000)
+	b.tokenAccount(vaultIn, syntheticUSDC, pool, 6, 90_000_000, 100_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, pool, 6, 70_000_000_000, 63_000_000_000)
+
+	registry := solanaswapgo.NewAMMRegistry()
+	require.NoError(t, registry.Register(solanaswapgo.AMMDescriptor{
+		ProgramID:        program,
+		Protocol:         "HandlerDex",
+		DiscriminatorLen: 1,
+		Discriminators:   []string{"2a"},
+		Accounts:         solanaswapgo.AMMAccounts{Pool: 0, PoolIn: 3, PoolOut: 4},
+	}))
+	handler := solanaswapgo.NewProtocolHandler("handlerdex", solanaswapgo.RoleAMM, []solana.PublicKey{program},
+		func(ctx *solanaswapgo.ProtocolContext) []solanaswapgo.SwapData {
+			return ctx.ParseTransfers("HandlerDex")
+		})
+
+	// WithProtocols leaves the handlers given to the config on
+	legs, err := parseWithConfig(t, b, solanaswapgo.NewParserConfig(
+		solanaswapgo.WithRegistry(registry),
+		solanaswapgo.WithHandlers(handler),
+		solanaswapgo.WithProtocols(solanaswapgo.PROTOCOL_RAYDIUM),
+	))
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, "HandlerDex", legs[0].Tx.Protocol)
+}
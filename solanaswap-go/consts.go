@@ -6,6 +6,7 @@ var (
 	JUPITER_PROGRAM_ID        = solana.MustPublicKeyFromBase58("JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4")
 	JUPITER_DCA_PROGRAM_ID    = solana.MustPublicKeyFromBase58("DCAK36VfExkPdAkYUQg6ewgxyinvcEyPLyHjRbmveKFw")
 	PUMP_FUN_PROGRAM_ID       = solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P")
+	PUMP_FUN_BSFD_PROGRAM_ID  = solana.MustPublicKeyFromBase58("BSfD6SHZigAfDWSjzD5Q41jw8LmKwtmjskPH9XW1mrRW")
 	PHOENIX_PROGRAM_ID        = solana.MustPublicKeyFromBase58("PhoeNiXZ8ByJGLkxNfZRnkUfjvmuYqLR89jjFHGqdXY")  // not supported yet
 	DFLOW_AGGREGATOR_V4       = solana.MustPublicKeyFromBase58("DF1ow4tspfHX9JwWJsAb9epbkA8hmpSEAtxXy1V27QBH") // DFlow
 	THREE_Q_ROUTER_PROGRAM_ID = solana.MustPublicKeyFromBase58("3q9RnxufDcorEPAmCeZumn8kveC832rYYmZSk5tcaCzM")
@@ -47,6 +48,8 @@ var (
 	PUMPFUN_AMM_PROGRAM_ID    = solana.MustPublicKeyFromBase58("pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA")
 
 	NATIVE_SOL_MINT_PROGRAM_ID = solana.MustPublicKeyFromBase58("So11111111111111111111111111111111111111112")
+	USDC_MINT                  = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	USDT_MINT                  = solana.MustPublicKeyFromBase58("Es9vMFrzaCERmJfrF4H7YWW4uY4ZWpjGe8E5E9ymW3Ff")
 )
 
 type SwapType string
@@ -62,6 +65,9 @@ const (
 	ZEROFI_SWAP SwapType = "ZeroFi"
 	HUMIDIDI    SwapType = "HumidiFi"
 	UNKNOWN  SwapType = "Unknown"
+	// INFERRED legs are built from the balance changes of the trader when
+	// no handler parsed the transaction.
+	INFERRED SwapType = "Inferred"
 )
 
 const (
@@ -71,4 +77,11 @@ const (
 
 	TxSwapSideBuy  = "buy"
 	TxSwapSideSell = "sell"
+
+	// MethodParsed legs come from a protocol handler, MethodInferred ones
+	// from the balance changes and MethodIntended ones from the arguments
+	// of a failed transaction.
+	MethodParsed   = "parsed"
+	MethodInferred = "inferred"
+	MethodIntended = "intended"
 )
@@ -0,0 +1,151 @@
+package solanaswapgo
+
+import (
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// BotFee is a transfer a trading bot or router program took from the trader
+// on top of the swap.
+type BotFee struct {
+	Program solana.PublicKey
+	// Recipient is the wallet that received the fee, the owner of the
+	// token account for token fees.
+	Recipient solana.PublicKey
+	// Mint is NATIVE_SOL_MINT_PROGRAM_ID for lamports.
+	Mint     solana.PublicKey
+	Amount   uint64
+	Decimals uint8
+}
+
+// Costs is everything the owner of a transaction paid besides the swap.
+type Costs struct {
+	// Fees are the network and priority fees and the Jito tips.
+	Fees    Fees
+	BotFees []BotFee
+	Rent    RentLedger
+}
+
+// Lamports returns the SOL the costs took from the trader: the fees, the
+// bot fees paid in SOL and the rent paid minus the rent refunded.
+func (c Costs) Lamports() int64 {
+	total := int64(c.Fees.Total()) - c.Rent.Net()
+	for _, fee := range c.BotFees {
+		if fee.Mint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) {
+			total += int64(fee.Amount)
+		}
+	}
+	return total
+}
+
+// Costs breaks down what the transaction cost its owner besides the swap.
+func (p *Parser) Costs() Costs {
+	return Costs{
+		Fees:    p.Fees(),
+		BotFees: p.botFees(),
+		Rent:    p.rent,
+	}
+}
+
+// attachCosts sets the Rent and Costs of the transaction on the swap when
+// its trader paid them: the fee payer, or the owner the bot fees and rent
+// are taken from.
+func (p *Parser) attachCosts(swapInfo *SwapInfo) bool {
+	if len(swapInfo.Signers) == 0 {
+		return false
+	}
+	trader := swapInfo.Signers[0]
+	if !trader.Equals(p.allAccountKeys[0]) && !trader.Equals(p.owner()) {
+		return false
+	}
+	costs := p.Costs()
+	swapInfo.Rent = p.rent
+	swapInfo.Costs = &costs
+	return true
+}
+
+// isRouterProgram tells the trading bots and routers of the config apart.
+func (p *Parser) isRouterProgram(progID solana.PublicKey) bool {
+	for _, router := range p.config.routers {
+		if progID.Equals(router) {
+			return true
+		}
+	}
+	return false
+}
+
+// botFees finds the transfers a router program made itself out of the
+// owner's accounts to someone else. The swaps it routes are made by the
+// AMMs it calls, one level deeper, so only the direct calls of the router
+// are looked at; nodes that do not report stack heights give none.
+func (p *Parser) botFees() []BotFee {
+	owner := p.owner()
+	var fees []BotFee
+	for _, set := range p.txMeta.InnerInstructions {
+		if int(set.Index) >= len(p.txInfo.Message.Instructions) {
+			continue
+		}
+		router := p.allAccountKeys[p.txInfo.Message.Instructions[set.Index].ProgramIDIndex]
+		if !p.isRouterProgram(router) {
+			continue
+		}
+		for _, inner := range set.Instructions {
+			if inner.StackHeight != 2 {
+				continue
+			}
+			instr := p.convertRPCToSolanaInstruction(inner)
+			var fee BotFee
+			var authority, destination string
+			switch {
+			case p.isTransfer(instr) || p.isSystemTransfer(instr):
+				transfer := p.processTransfer(instr)
+				if transfer == nil {
+					continue
+				}
+				authority, destination = transfer.Info.Authority, transfer.Info.Destination
+				fee = BotFee{Mint: mintFromString(transfer.Mint), Amount: transfer.Info.Amount, Decimals: transfer.Decimals}
+			case p.isTransferCheck(instr):
+				transfer := p.processTransferCheck(instr)
+				if transfer == nil {
+					continue
+				}
+				amount, err := strconv.ParseUint(transfer.Info.TokenAmount.Amount, 10, 64)
+				if err != nil {
+					continue
+				}
+				authority, destination = transfer.Info.Authority, transfer.Info.Destination
+				fee = BotFee{Mint: mintFromString(transfer.Info.Mint), Amount: amount, Decimals: transfer.Info.TokenAmount.Decimals}
+			default:
+				continue
+			}
+			to, err := solana.PublicKeyFromBase58(destination)
+			if err != nil || authority != owner.String() {
+				continue
+			}
+			fee.Program = router
+			fee.Recipient = p.accountOwner(to)
+			if fee.Recipient.Equals(owner) {
+				continue
+			}
+			fees = append(fees, fee)
+		}
+	}
+	return fees
+}
+
+// accountOwner returns the owner of a token account, or the account itself
+// when it holds no tokens.
+func (p *Parser) accountOwner(account solana.PublicKey) solana.PublicKey {
+	i, ok := p.accountIndex(account)
+	if !ok {
+		return account
+	}
+	for _, balances := range []map[uint16]*rpc.TokenBalance{p.postBalance, p.preBalance} {
+		if balance, ok := balances[uint16(i)]; ok && balance.Owner != nil {
+			return *balance.Owner
+		}
+	}
+	return account
+}
@@ -0,0 +1,74 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+// buildBotSwap is a Raydium CPMM swap made through a trading bot that
+// takes a SOL fee and a USDC fee before routing, with a priority fee, a
+// Jito tip and a new token account for the output.
+func buildBotSwap(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "bot-swap")
+	pool := b.account("pool")
+	authority := b.account("authority")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+	botWallet, botUSDC := b.account("bot-wallet"), b.account("bot-usdc")
+	tip := solanaswapgo.JitoTipAccounts[1]
+
+	b.addOuter(solana.ComputeBudget, nil, computeUnitLimitData(200_000))
+	b.addOuter(solana.ComputeBudget, nil, computeUnitPriceData(50_000))
+	ata := b.addOuter(solana.SPLAssociatedTokenAccountProgramID, []solana.PublicKey{b.signer, userOut, b.signer, syntheticMint}, []byte{1})
+	b.addInner(ata, 2, solana.SystemProgramID, []solana.PublicKey{b.signer, userOut}, createAccountData(tokenAccountRent, 165, solana.TokenProgramID))
+
+	ix := b.addOuter(solanaswapgo.BLOOM_PROGRAM_ID, []solana.PublicKey{b.signer}, []byte{1})
+	b.addInner(ix, 2, solana.SystemProgramID, []solana.PublicKey{b.signer, botWallet}, systemTransferData(250_000))
+	b.addTransfer(ix, 2, userIn, botUSDC, b.signer, 100_000)
+	b.addInner(ix, 2, solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userIn, userOut, vaultIn, vaultOut,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(ix, 3, userIn, vaultIn, b.signer, 25_000_000)
+	b.addTransfer(ix, 3, vaultOut, userOut, authority, 61_234_567_890)
+	b.addOuter(solana.SystemProgramID, []solana.PublicKey{b.signer, tip}, systemTransferData(1_000_000))
+
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 100_000_000, 74_900_000)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 61_234_567_890)
+	b.tokenAccount(botUSDC, syntheticUSDC, botWallet, 6, 0, 100_000)
+	b.tokenAccount(vaultIn, syntheticUSDC, authority, 6, 5_000_000_000, 5_025_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, authority, 6, 12_000_000_000_000, 11_938_765_432_110)
+	b.meta.Fee = 5000 + 10_000
+	b.lamports(b.signer, 2_000_000_000, 2_000_000_000-15_000-tokenAccountRent-250_000-1_000_000)
+	b.lamports(botWallet, 0, 250_000)
+	b.lamports(tip, 0, 1_000_000)
+	return b
+}
+
+func TestCosts(t *testing.T) {
+	b := buildBotSwap(t)
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+
+	costs := parser.Costs()
+	require.Equal(t, uint64(5000), costs.Fees.Network)
+	require.Equal(t, uint64(10_000), costs.Fees.Priority)
+	require.Equal(t, uint64(1_000_000), costs.Fees.Tip)
+	require.Equal(t, solanaswapgo.RentLedger{Paid: tokenAccountRent}, costs.Rent)
+	require.Equal(t, []solanaswapgo.BotFee{
+		{Program: solanaswapgo.BLOOM_PROGRAM_ID, Recipient: b.account("bot-wallet"), Mint: solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, Amount: 250_000, Decimals: 9},
+		{Program: solanaswapgo.BLOOM_PROGRAM_ID, Recipient: b.account("bot-wallet"), Mint: syntheticUSDC, Amount: 100_000, Decimals: 6},
+	}, costs.BotFees)
+	require.Equal(t, int64(15_000+1_000_000+tokenAccountRent+250_000), costs.Lamports())
+
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, uint64(25_000_000), legs[0].Tx.InputAmount)
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, &costs, info.Costs)
+}
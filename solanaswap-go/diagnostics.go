@@ -0,0 +1,120 @@
+package solanaswapgo
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	SkipReasonNoHandler        = "no handler"
+	SkipReasonAggregator       = "outer AMM pass skipped, transaction claimed by an aggregator"
+	SkipReasonAlreadyProcessed = "already parsed with an earlier instruction"
+	SkipReasonNoSwaps          = "handler found no swaps"
+	SkipReasonNotSwap          = "not a swap or liquidity instruction"
+)
+
+// InstructionDiagnostic records what happened to one examined instruction.
+type InstructionDiagnostic struct {
+	OuterIndex int
+	// InnerIndex is -1 for outer instructions.
+	InnerIndex int
+	ProgramID  solana.PublicKey
+	// Handler is the name of the ProtocolHandler that claimed the
+	// instruction, empty when none did.
+	Handler    string
+	Swaps      int
+	SkipReason string
+	// SkipErr is the cause of the skip, such as ErrDiscriminatorUnmatched
+	// for instructions that are not swaps. It is not a parse error and is
+	// not part of Err.
+	SkipErr error
+	Errors  []error
+}
+
+// Diagnostics lists every instruction examined by ParseTransaction.
+type Diagnostics struct {
+	Signature    solana.Signature
+	Status       TxStatus
+	Instructions []*InstructionDiagnostic
+}
+
+// Err joins every error recorded while parsing.
+func (d *Diagnostics) Err() error {
+	var errs []error
+	for _, ix := range d.Instructions {
+		errs = append(errs, ix.Errors...)
+	}
+	return errors.Join(errs...)
+}
+
+// Gaps returns the instructions that may hold a lost swap: the ones no
+// handler claimed, ignoring well-known infrastructure programs, and the
+// claimed ones that failed.
+func (d *Diagnostics) Gaps() []*InstructionDiagnostic {
+	var gaps []*InstructionDiagnostic
+	for _, ix := range d.Instructions {
+		switch {
+		case len(ix.Errors) > 0 && ix.Swaps == 0:
+			gaps = append(gaps, ix)
+		case ix.Handler == "" && ix.SkipReason == SkipReasonNoHandler && !isInfrastructureProgram(ix.ProgramID):
+			gaps = append(gaps, ix)
+		}
+	}
+	return gaps
+}
+
+func (d *Diagnostics) add(outerIndex, innerIndex int, progID solana.PublicKey) *InstructionDiagnostic {
+	ix := &InstructionDiagnostic{OuterIndex: outerIndex, InnerIndex: innerIndex, ProgramID: progID}
+	if d != nil {
+		d.Instructions = append(d.Instructions, ix)
+	}
+	return ix
+}
+
+func (d *Diagnostics) finish() {
+	for _, ix := range d.Instructions {
+		switch {
+		case ix.SkipReason == SkipReasonNotSwap && ix.Swaps > 0:
+			ix.SkipReason, ix.SkipErr = "", nil
+		case ix.SkipReason != "":
+		case ix.Handler == "":
+			ix.SkipReason = SkipReasonNoHandler
+		case ix.Swaps == 0 && len(ix.Errors) == 0:
+			ix.SkipReason = SkipReasonNoSwaps
+		}
+	}
+	sort.SliceStable(d.Instructions, func(i, j int) bool {
+		a, b := d.Instructions[i], d.Instructions[j]
+		if a.OuterIndex != b.OuterIndex {
+			return a.OuterIndex < b.OuterIndex
+		}
+		return a.InnerIndex < b.InnerIndex
+	})
+}
+
+var infrastructurePrograms = []solana.PublicKey{
+	solana.SystemProgramID,
+	solana.ComputeBudget,
+	solana.TokenProgramID,
+	solana.Token2022ProgramID,
+	solana.SPLAssociatedTokenAccountProgramID,
+	solana.MemoProgramID,
+}
+
+func isInfrastructureProgram(progID solana.PublicKey) bool {
+	for _, prog := range infrastructurePrograms {
+		if progID.Equals(prog) {
+			return true
+		}
+	}
+	return false
+}
+
+// noteError attaches err to the instruction currently being parsed.
+func (p *Parser) noteError(err error) {
+	if p.currentDiag != nil && err != nil {
+		p.currentDiag.Errors = append(p.currentDiag.Errors, err)
+	}
+}
@@ -0,0 +1,74 @@
+package solanaswapgo_test
+
+import (
+	"errors"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTransactionWithDiagnostics(t *testing.T) {
+	b := buildRaydiumCPMMSwap(t)
+	unknown := b.account("unknown-program")
+	b.addOuter(solana.ComputeBudget, nil, []byte{2, 0, 0, 0, 0})
+	b.addOuter(unknown, []solana.PublicKey{b.signer}, []byte{1})
+
+	parser, err := solanaswapgo.NewParser(b.result())
+	require.NoError(t, err)
+	legs, diags, err := parser.ParseTransactionWithDiagnostics()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.NoError(t, diags.Err())
+
+	require.Len(t, diags.Instructions, 3)
+	require.Equal(t, solanaswapgo.PROTOCOL_RAYDIUM, diags.Instructions[0].Handler)
+	require.Equal(t, 1, diags.Instructions[0].Swaps)
+	require.Equal(t, -1, diags.Instructions[0].InnerIndex)
+	require.Equal(t, solanaswapgo.SkipReasonNoHandler, diags.Instructions[1].SkipReason)
+
+	gaps := diags.Gaps()
+	require.Len(t, gaps, 1)
+	require.Equal(t, unknown, gaps[0].ProgramID)
+}
+
+func TestParseTransactionWithDiagnostics_Errors(t *testing.T) {
+	// the swap matched but the output vault has no balance to read
+	b := buildRaydiumCPMMSwap(t)
+	b.meta.PostTokenBalances = b.meta.PostTokenBalances[:3]
+
+	parser, err := solanaswapgo.NewParser(b.result())
+	require.NoError(t, err)
+	_, diags, err := parser.ParseTransactionWithDiagnostics()
+	require.NoError(t, err)
+
+	require.True(t, errors.Is(diags.Err(), solanaswapgo.ErrMissingPostBalance))
+	gaps := diags.Gaps()
+	require.Len(t, gaps, 1)
+	require.Equal(t, solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, gaps[0].ProgramID)
+}
+
+func TestParseTransactionWithDiagnostics_NotSwap(t *testing.T) {
+	// an admin call of the pool next to the swap is not an error, even
+	// for a strict parser
+	b := buildRaydiumCPMMSwap(t)
+	recipient, feeVault := b.account("fund-recipient"), b.account("fee-vault")
+	ix := b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, b.account("authority"), b.account("pool"), b.account("amm-config"),
+		feeVault, b.account("vault-token"), syntheticUSDC, syntheticMint, recipient,
+	}, anchorDiscriminator("collect_fund_fee"))
+	b.addTransfer(ix, 2, feeVault, recipient, b.account("authority"), 1_000)
+	b.tokenAccount(feeVault, syntheticUSDC, b.account("authority"), 6, 1_000, 0)
+	b.tokenAccount(recipient, syntheticUSDC, b.account("treasury"), 6, 0, 1_000)
+
+	parser, err := solanaswapgo.NewParser(b.result(), solanaswapgo.WithStrict(true))
+	require.NoError(t, err)
+	legs, diags, err := parser.ParseTransactionWithDiagnostics()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.NoError(t, diags.Err())
+	require.Equal(t, solanaswapgo.SkipReasonNotSwap, diags.Instructions[1].SkipReason)
+	require.ErrorIs(t, diags.Instructions[1].SkipErr, solanaswapgo.ErrDiscriminatorUnmatched)
+	require.Empty(t, diags.Gaps())
+}
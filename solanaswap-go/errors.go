@@ -0,0 +1,19 @@
+package solanaswapgo
+
+import "errors"
+
+// Errors reported while parsing an instruction. They are wrapped with
+// details, use errors.Is to match them.
+var (
+	ErrMissingTransaction     = errors.New("transaction is missing")
+	ErrMissingMeta            = errors.New("transaction meta is missing")
+	ErrUnknownProgram         = errors.New("unknown program")
+	ErrDiscriminatorUnmatched = errors.New("discriminator unmatched")
+	ErrInvalidInstructionData = errors.New("invalid instruction data")
+	ErrAccountIndexOutOfRange = errors.New("account index out of range")
+	ErrMissingPostBalance     = errors.New("no postBalance for account")
+	ErrMintMismatch           = errors.New("mint mismatch")
+	ErrNoValidSwaps           = errors.New("no valid swaps found")
+	ErrInconsistentParse      = errors.New("parsed amounts disagree with balance changes")
+	ErrNoArbitrage            = errors.New("legs do not form an arbitrage cycle")
+)
@@ -1,7 +1,6 @@
 package solanaswapgo
 
 import (
-	"encoding/json"
 	"fmt"
 
 	ag_binary "github.com/gagliardetto/binary"
@@ -36,11 +35,12 @@ func (p *Parser) processJupiterSwaps(instructionIndex int) []SwapData {
 				if p.isJupiterRouteEventInstruction(p.convertRPCToSolanaInstruction(innerInstruction)) {
 					eventData, err := p.parseJupiterRouteEventInstruction(p.convertRPCToSolanaInstruction(innerInstruction))
 					if err != nil {
-						p.Log.Errorf("error processing Pumpfun trade event: %s", err)
+						p.log.Error("failed to decode jupiter route event", "err", err)
+						p.noteError(err)
 					}
 					if eventData != nil {
 						tx := p.parseJupiterTxInfo(eventData, innerInstructionSet, last)
-						swaps = append(swaps, SwapData{Type: JUPITER, Data: eventData, Tx: tx})
+						swaps = append(swaps, SwapData{Type: JUPITER, Data: eventData, Tx: tx, inner: i})
 					}
 					last = i
 				}
@@ -72,20 +72,10 @@ func (p *Parser) parseJupiterRouteEventInstruction(instruction solana.CompiledIn
 		return nil, fmt.Errorf("error decoding jupiter swap event: %s", err)
 	}
 
-	inputMintDecimals, exists := p.splDecimalsMap[jupSwapEvent.InputMint.String()]
-	if !exists {
-		inputMintDecimals = 0
-	}
-
-	outputMintDecimals, exists := p.splDecimalsMap[jupSwapEvent.OutputMint.String()]
-	if !exists {
-		outputMintDecimals = 0
-	}
-
 	return &JupiterSwapEventData{
 		JupiterSwapEvent:   *jupSwapEvent,
-		InputMintDecimals:  inputMintDecimals,
-		OutputMintDecimals: outputMintDecimals,
+		InputMintDecimals:  p.mintDecimals(jupSwapEvent.InputMint),
+		OutputMintDecimals: p.mintDecimals(jupSwapEvent.OutputMint),
 	}, nil
 }
 
@@ -101,28 +91,31 @@ func (p *Parser) extractSPLDecimals() error {
 	mintToDecimals := make(map[string]uint8)
 
 	for _, accountInfo := range p.txMeta.PostTokenBalances {
-		if !accountInfo.Mint.IsZero() {
+		if !accountInfo.Mint.IsZero() && accountInfo.UiTokenAmount != nil {
 			mintAddress := accountInfo.Mint.String()
 			mintToDecimals[mintAddress] = uint8(accountInfo.UiTokenAmount.Decimals)
 		}
 	}
 
+	// transferChecked carries the decimals of its mint
+	var unknownMints []solana.PublicKey
 	processInstruction := func(instr solana.CompiledInstruction) {
-		if !p.allAccountKeys[instr.ProgramIDIndex].Equals(solana.TokenProgramID) {
+		if !isTokenProgram(p.allAccountKeys[instr.ProgramIDIndex]) || len(instr.Accounts) < 3 {
 			return
 		}
-
-		if len(instr.Data) == 0 || (instr.Data[0] != 3 && instr.Data[0] != 12) {
+		transfer, ok := decodeCheckedTransfer(instr.Data)
+		if !ok {
 			return
 		}
 
-		if len(instr.Accounts) < 3 {
+		mint := p.allAccountKeys[instr.Accounts[1]]
+		if _, exists := mintToDecimals[mint.String()]; exists {
 			return
 		}
-
-		mint := p.allAccountKeys[instr.Accounts[1]].String()
-		if _, exists := mintToDecimals[mint]; !exists {
-			mintToDecimals[mint] = 0
+		if transfer.hasDecimals {
+			mintToDecimals[mint.String()] = transfer.decimals
+		} else {
+			unknownMints = append(unknownMints, mint)
 		}
 	}
 
@@ -141,54 +134,109 @@ func (p *Parser) extractSPLDecimals() error {
 	}
 
 	p.splDecimalsMap = mintToDecimals
+	p.mintInfos = make(map[solana.PublicKey]MintInfo)
+	for _, mint := range unknownMints {
+		p.needMint(mint)
+	}
 
 	return nil
 }
 
-// parseJupiterEvents parses Jupiter swap events and returns a SwapInfo representing the entire route
-func parseJupiterEvents(events []SwapData) (*SwapInfo, error) {
-	if len(events) == 0 {
-		return nil, fmt.Errorf("no events provided")
+// needMint records a mint the transaction gives no decimals for, it is
+// looked up with the others by resolveMints.
+func (p *Parser) needMint(mint solana.PublicKey) {
+	if mint.IsZero() {
+		return
 	}
-
-	var firstSwap, lastSwap *JupiterSwapEventData
-
-	for i, event := range events {
-		if event.Type != JUPITER {
-			continue
+	if _, ok := p.splDecimalsMap[mint.String()]; ok {
+		return
+	}
+	if _, ok := p.mintInfos[mint]; ok {
+		return
+	}
+	for _, pending := range p.pendingMints {
+		if pending.Equals(mint) {
+			return
 		}
+	}
+	p.pendingMints = append(p.pendingMints, mint)
+}
 
-		var jupiterEvent JupiterSwapEventData
-		eventData, err := json.Marshal(event.Data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal event data: %v", err)
+// resolveMints asks the MintInfoProvider about every mint the parse met
+// without decimals in a single call, then fills in the decimals of the
+// legs and events and the extensions of the Token-2022 mints. Decimals
+// needed later, when the swaps are processed, are queued here too: no
+// lookup happens after this call.
+func (p *Parser) resolveMints(swaps []SwapData) {
+	for _, swap := range swaps {
+		switch data := swap.Data.(type) {
+		case *PumpfunTradeEvent:
+			p.mintDecimals(data.Mint)
+		case *MoonshotTradeInstructionWithMint:
+			p.mintDecimals(data.Mint)
 		}
-
-		if err := json.Unmarshal(eventData, &jupiterEvent); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal Jupiter event data: %v", err)
+	}
+	pending := p.pendingMints
+	p.pendingMints = nil
+	p.lookupMints(pending)
+	for _, swap := range swaps {
+		if tx := swap.Tx; tx != nil {
+			p.fillDecimals(tx.InputMint, &tx.InputMintDecimals)
+			p.fillDecimals(tx.OutputMint, &tx.OutputMintDecimals)
 		}
-
-		if i == 0 {
-			firstSwap = &jupiterEvent
+		if event, ok := swap.Data.(*JupiterSwapEventData); ok {
+			p.fillDecimals(event.InputMint, &event.InputMintDecimals)
+			p.fillDecimals(event.OutputMint, &event.OutputMintDecimals)
 		}
-		lastSwap = &jupiterEvent
 	}
+	p.setMintExtensions()
+}
 
-	if firstSwap == nil || lastSwap == nil {
-		return nil, fmt.Errorf("no valid Jupiter swaps found")
+func (p *Parser) fillDecimals(mint solana.PublicKey, decimals *uint8) {
+	if *decimals == 0 {
+		*decimals = p.splDecimalsMap[mint.String()]
 	}
+}
 
-	swapInfo := &SwapInfo{
-		AMMs:             []string{string(JUPITER)},
-		TokenInMint:      firstSwap.InputMint,
-		TokenInAmount:    firstSwap.InputAmount,
-		TokenInDecimals:  firstSwap.InputMintDecimals,
-		TokenOutMint:     lastSwap.OutputMint,
-		TokenOutAmount:   lastSwap.OutputAmount,
-		TokenOutDecimals: lastSwap.OutputMintDecimals,
+// lookupMints asks the MintInfoProvider about mints in one call, with the
+// context of WithContext, filling in the decimals the transaction has no
+// balance for.
+func (p *Parser) lookupMints(mints []solana.PublicKey) {
+	if p.config.mintInfo == nil || len(mints) == 0 {
+		return
+	}
+	infos, err := p.config.mintInfo.MintInfo(p.config.ctx, mints)
+	if err != nil {
+		p.log.Warn("failed to look up mint info", "mints", len(mints), "err", err)
 	}
+	// unknown mints are kept with a zero Mint so they are asked only once
+	for _, mint := range mints {
+		info, ok := infos[mint]
+		if ok {
+			info.Mint = mint
+		}
+		p.mintInfos[mint] = info
+		if _, exists := p.splDecimalsMap[mint.String()]; ok && !exists {
+			p.splDecimalsMap[mint.String()] = info.Decimals
+		}
+	}
+}
 
-	return swapInfo, nil
+// mintDecimals returns the decimals of mint, or 0 when the transaction
+// does not know it. Such mints are looked up by resolveMints once the
+// instructions are parsed.
+func (p *Parser) mintDecimals(mint solana.PublicKey) uint8 {
+	if decimals, ok := p.splDecimalsMap[mint.String()]; ok {
+		return decimals
+	}
+	p.needMint(mint)
+	return 0
+}
+
+// knownDecimals returns the decimals of mint found in the transaction or
+// by resolveMints, 0 when neither knows it.
+func (p *Parser) knownDecimals(mint solana.PublicKey) uint8 {
+	return p.splDecimalsMap[mint.String()]
 }
 
 func (p *Parser) parseJupiterTxInfo(eventData *JupiterSwapEventData, instr rpc.InnerInstruction, last int) *TxInfo {
@@ -214,23 +262,29 @@ func (p *Parser) parseJupiterTxInfo(eventData *JupiterSwapEventData, instr rpc.I
 		if p.setTxPoolInfo(progID, tx, p.convertRPCToSolanaInstruction(instruction)) != nil {
 			continue
 		}
-		tx.Owner = *p.txInfo.Message.Signers().Last()
+		tx.Owner = p.owner()
 		tx.Router = p.allAccountKeys[p.txInfo.Message.Instructions[instr.Index].ProgramIDIndex]
-		tx.Index = uint(instr.Index*256) + uint(n)
+		tx.setPosition(int(instr.Index), n)
 		return tx
 	}
 	return nil
 }
 
-func (p *Parser) extractAccountPostBalance() error {
-	p.postBalance = make(map[uint16]*rpc.TokenBalance)
-	for _, accountInfo := range p.txMeta.PostTokenBalances {
+func (p *Parser) extractAccountBalances() error {
+	p.postBalance = tokenBalancesByAccount(p.txMeta.PostTokenBalances)
+	p.preBalance = tokenBalancesByAccount(p.txMeta.PreTokenBalances)
+	return nil
+}
+
+func tokenBalancesByAccount(balances []rpc.TokenBalance) map[uint16]*rpc.TokenBalance {
+	byAccount := make(map[uint16]*rpc.TokenBalance)
+	for _, accountInfo := range balances {
 		if !accountInfo.Mint.IsZero() {
 			if accountInfo.UiTokenAmount == nil || accountInfo.UiTokenAmount.Amount == "" {
 				continue
 			}
-			p.postBalance[accountInfo.AccountIndex] = &accountInfo
+			byAccount[accountInfo.AccountIndex] = &accountInfo
 		}
 	}
-	return nil
+	return byAccount
 }
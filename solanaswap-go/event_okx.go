@@ -2,6 +2,7 @@ package solanaswapgo
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 
 	"github.com/mr-tron/base58"
@@ -23,18 +24,20 @@ func (p *Parser) processOKXSwaps(instructionIndex int) []SwapData {
 	programID := p.allAccountKeys[parentInstruction.ProgramIDIndex]
 
 	if !programID.Equals(OKX_DEX_ROUTER_PROGRAM_ID) {
-		p.Log.Warnf("instruction %d skipped: not okx dex router program", instructionIndex)
+		p.log.Warn("instruction skipped: not okx dex router program")
 		return nil
 	}
 
 	if len(parentInstruction.Data) < 8 {
-		p.Log.Warnf("instruction %d skipped: data too short (%d)", instructionIndex, len(parentInstruction.Data))
+		p.log.Warn("instruction skipped: data too short", "len", len(parentInstruction.Data))
+		p.noteError(fmt.Errorf("%w: okx instruction is %d bytes", ErrInvalidInstructionData, len(parentInstruction.Data)))
 		return nil
 	}
 
 	decodedBytes, err := base58.Decode(parentInstruction.Data.String())
 	if err != nil {
-		p.Log.Errorf("failed to decode okx swap instruction %d: %s", instructionIndex, err)
+		p.log.Error("failed to decode okx swap instruction", "err", err)
+		p.noteError(fmt.Errorf("%w: %v", ErrInvalidInstructionData, err))
 		return nil
 	}
 
@@ -71,7 +74,8 @@ func (p *Parser) processOKXSwaps(instructionIndex int) []SwapData {
 			//	p.Log.Infof("successfully processed %d swaps with unknown discriminator", len(swaps))
 			return swaps
 		}
-		p.Log.Warnf("no swaps found with unknown discriminator %x", discriminator)
+		p.log.Warn("no swaps found with unknown discriminator", "discriminator", hex.EncodeToString(discriminator))
+		p.noteError(fmt.Errorf("%w: okx %x", ErrDiscriminatorUnmatched, discriminator))
 		return nil
 	}
 }
@@ -84,7 +88,7 @@ func (p *Parser) processOKXRouterSwaps(instructionIndex int) []SwapData {
 	innerInstructions := p.getInnerInstructions(instructionIndex)
 	// p.Log.Infof("processing okx router swaps for instruction %d: %d inner instructions", instructionIndex, len(innerInstructions))
 	if len(innerInstructions) == 0 {
-		p.Log.Warnf("no inner instructions for instruction %d", instructionIndex)
+		p.log.Warn("no inner instructions")
 		return swaps
 	}
 
@@ -42,7 +42,8 @@ func (p *Parser) processPumpfunSwaps(instructionIndex int) []SwapData {
 				if p.isPumpFunTradeEventInstruction(p.convertRPCToSolanaInstruction(innerInstruction)) {
 					eventData, err := p.parsePumpfunTradeEventInstruction(p.convertRPCToSolanaInstruction(innerInstruction))
 					if err != nil {
-						p.Log.Errorf("error processing Pumpfun trade event: %s", err)
+						p.log.Error("failed to decode pumpfun trade event", "err", err)
+						p.noteError(err)
 					}
 					if eventData != nil {
 						swaps = append(swaps, SwapData{Type: PUMP_FUN, Data: eventData})
@@ -66,13 +67,17 @@ func (p *Parser) processPumpfunAMMSwaps(PInstructionIndex int, isInner bool) []S
 		case p.isPumpFunAMMBuyDiscriminator(inner) || p.isPumpFunAMMBuyExactQuoteInDiscriminator(inner):
 			// parse pool
 			tx := p.processPumFumAMMBuySwaps(pProgID, inner)
-			tx.Index = uint(PInstructionIndex*256) + uint(i)
+			if tx == nil {
+				continue
+			}
+			tx.setPosition(PInstructionIndex, i)
 			// parse event
 			for x := i + 1; x < len(inners); x++ {
 				if p.isPumpFunAMMSwapEventInstruction(inners[x]) {
 					err := p.parsePumpfunAMMSwapEvent(tx, inners[x])
 					if err != nil {
-						p.Log.Errorf("error processing Pumpfun amm swap event: %s", err)
+						p.log.Error("failed to decode pumpfun amm swap event", "err", err)
+						p.noteError(err)
 						return nil
 					}
 					if tx != nil {
@@ -84,13 +89,17 @@ func (p *Parser) processPumpfunAMMSwaps(PInstructionIndex int, isInner bool) []S
 			// insert event
 		case p.isPumpFunAMMSellDiscriminator(inner) || p.isPumpFunAMMSellExactInDiscriminator(inner):
 			tx := p.processPumpFunAMMSellSwaps(pProgID, inner)
-			tx.Index = uint(PInstructionIndex*256) + uint(i)
+			if tx == nil {
+				continue
+			}
+			tx.setPosition(PInstructionIndex, i)
 			// parse event
 			for x := i + 1; x < len(inners); x++ {
 				if p.isPumpFunAMMSwapEventInstruction(inners[x]) {
 					err := p.parsePumpfunAMMSwapEvent(tx, inners[x])
 					if err != nil {
-						p.Log.Errorf("error processing Pumpfun amm swap event: %s", err)
+						p.log.Error("failed to decode pumpfun amm swap event", "err", err)
+						p.noteError(err)
 						return nil
 					}
 					if tx != nil {
@@ -115,7 +124,10 @@ func (p *Parser) processPumpfunAMMSwaps(PInstructionIndex int, isInner bool) []S
 	default:
 		return nil
 	}
-	tx.Index = uint(PInstructionIndex * 256)
+	if tx == nil {
+		return nil
+	}
+	tx.setPosition(PInstructionIndex, -1)
 
 	for _, innerInstructionSet := range p.txMeta.InnerInstructions {
 		if innerInstructionSet.Index == uint16(PInstructionIndex) {
@@ -137,7 +149,8 @@ func (p *Parser) processPumpfunAMMSwaps(PInstructionIndex int, isInner bool) []S
 				if p.isPumpFunAMMSwapEventInstruction(p.convertRPCToSolanaInstruction(innerInstruction)) {
 					err := p.parsePumpfunAMMSwapEvent(tx, p.convertRPCToSolanaInstruction(innerInstruction))
 					if err != nil {
-						p.Log.Errorf("error processing Pumpfun trade event: %s", err)
+						p.log.Error("failed to decode pumpfun trade event", "err", err)
+						p.noteError(err)
 						return nil
 					}
 					swaps = append(swaps, SwapData{Type: PUMP_FUN, Data: nil, Tx: tx})
@@ -192,6 +205,9 @@ func (p *Parser) setPumpFunSwapTxInfo(tx *TxInfo, instructIndex int) error {
 	if len(instr.Data) < 8 {
 		return fmt.Errorf("instruction data too short")
 	}
+	if len(instr.Accounts) < 9 {
+		return fmt.Errorf("%w: pumpfun amm instruction has %d accounts", ErrAccountIndexOutOfRange, len(instr.Accounts))
+	}
 
 	poolIndex := 0
 	baseMintIndex := 3
@@ -219,8 +235,8 @@ func (p *Parser) setPumpFunSwapTxInfo(tx *TxInfo, instructIndex int) error {
 		tx.PoolIn = p.allAccountKeys[instr.Accounts[basePoolIndex]]
 		tx.PoolOut = p.allAccountKeys[instr.Accounts[quotePoolIndex]]
 	}
-	tx.InputMintDecimals = p.splDecimalsMap[tx.InputMint.String()]
-	tx.OutputMintDecimals = p.splDecimalsMap[tx.OutputMint.String()]
+	tx.InputMintDecimals = p.mintDecimals(tx.InputMint)
+	tx.OutputMintDecimals = p.mintDecimals(tx.OutputMint)
 	tx.Protocol = PROTOCOL_PUMPFUN
 
 	return nil
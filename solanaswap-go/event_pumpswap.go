@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"math/big"
+	"time"
 
 	ag_binary "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
@@ -92,6 +93,7 @@ func (p *Parser) parsePumpfunAMMSwapEvent(tx *TxInfo, instruction solana.Compile
 		tx.OutputAmount = buyEvent.BaseAmountOut
 		tx.PoolInAmount = new(big.Int).SetUint64(buyEvent.PoolQuoteTokenReserves)
 		tx.PoolOutAmount = new(big.Int).SetUint64(buyEvent.PoolBaseTokenReserves)
+		tx.ProtocolTimestamp = time.Unix(int64(buyEvent.Timestamp), 0)
 
 		return nil
 	}
@@ -105,6 +107,7 @@ func (p *Parser) parsePumpfunAMMSwapEvent(tx *TxInfo, instruction solana.Compile
 		tx.OutputAmount = sellEvent.UserQuoteAmountOut
 		tx.PoolInAmount = new(big.Int).SetUint64(sellEvent.PoolBaseTokenReserves)
 		tx.PoolOutAmount = new(big.Int).SetUint64(sellEvent.PoolQuoteTokenReserves)
+		tx.ProtocolTimestamp = time.Unix(int64(sellEvent.Timestamp), 0)
 
 		return nil
 	}
@@ -140,17 +143,20 @@ func (p *Parser) isPumpFunAMMBuyDiscriminator(instr solana.CompiledInstruction)
 }
 
 func (p *Parser) processPumFumAMMBuySwaps(router solana.PublicKey, instruction solana.CompiledInstruction) *TxInfo {
+	if len(instruction.Accounts) < 9 {
+		return nil
+	}
 	inputMint := p.allAccountKeys[instruction.Accounts[4]]
 	outputMint := p.allAccountKeys[instruction.Accounts[3]]
 	tx := &TxInfo{
 		Type:               TxTypeSwap,
 		Amm:                p.allAccountKeys[instruction.ProgramIDIndex],
 		Router:             router,
-		Owner:              *p.txInfo.Message.Signers().Last(),
+		Owner:              p.owner(),
 		InputMint:          inputMint,
-		InputMintDecimals:  p.splDecimalsMap[inputMint.String()],
+		InputMintDecimals:  p.mintDecimals(inputMint),
 		OutputMint:         outputMint,
-		OutputMintDecimals: p.splDecimalsMap[outputMint.String()],
+		OutputMintDecimals: p.mintDecimals(outputMint),
 		Pool:               p.allAccountKeys[instruction.Accounts[0]],
 		PoolIn:             p.allAccountKeys[instruction.Accounts[8]],
 		PoolOut:            p.allAccountKeys[instruction.Accounts[7]],
@@ -160,17 +166,20 @@ func (p *Parser) processPumFumAMMBuySwaps(router solana.PublicKey, instruction s
 }
 
 func (p *Parser) processPumpFunAMMSellSwaps(router solana.PublicKey, instruction solana.CompiledInstruction) *TxInfo {
+	if len(instruction.Accounts) < 9 {
+		return nil
+	}
 	inputMint := p.allAccountKeys[instruction.Accounts[3]]
 	outputMint := p.allAccountKeys[instruction.Accounts[4]]
 	tx := &TxInfo{
 		Type:               TxTypeSwap,
 		Amm:                p.allAccountKeys[instruction.ProgramIDIndex],
 		Router:             router,
-		Owner:              *p.txInfo.Message.Signers().Last(),
+		Owner:              p.owner(),
 		InputMint:          inputMint,
-		InputMintDecimals:  p.splDecimalsMap[inputMint.String()],
+		InputMintDecimals:  p.mintDecimals(inputMint),
 		OutputMint:         outputMint,
-		OutputMintDecimals: p.splDecimalsMap[outputMint.String()],
+		OutputMintDecimals: p.mintDecimals(outputMint),
 		Pool:               p.allAccountKeys[instruction.Accounts[0]],
 		PoolIn:             p.allAccountKeys[instruction.Accounts[7]],
 		PoolOut:            p.allAccountKeys[instruction.Accounts[8]],
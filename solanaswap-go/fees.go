@@ -0,0 +1,97 @@
+package solanaswapgo
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Compute budget instructions, see solana-sdk compute_budget.rs.
+const (
+	computeBudgetSetComputeUnitLimit = 2
+	computeBudgetSetComputeUnitPrice = 3
+
+	defaultInstructionComputeUnits = 200_000
+	maxComputeUnitLimit            = 1_400_000
+)
+
+// JitoTipAccounts receive the tips paid to Jito block engines.
+var JitoTipAccounts = []solana.PublicKey{
+	solana.MustPublicKeyFromBase58("96gYZGLnJYVFmbjzopPSU6QiEV5fGqZNyN9nmNhvrZU5"),
+	solana.MustPublicKeyFromBase58("HFqU5x63VTqvQss8hp11i4wVV8bD44PvwucfZ2bU7gRe"),
+	solana.MustPublicKeyFromBase58("Cw8CFyM9FkoMi7K7Crf6HNQqf4uEMzpKw6QNghXLvLkY"),
+	solana.MustPublicKeyFromBase58("ADaUMid9yfUytqMBgopwjb2DTLSokTSzL1zt6iGPaS49"),
+	solana.MustPublicKeyFromBase58("DfXygSm4jCyNCybVYYK6DwvWqjKee8pbDmJGcLWNDXjh"),
+	solana.MustPublicKeyFromBase58("ADuUkR4vqLUMWXxW9gh6D6L8pMSawimctcNZ5pGwDcEt"),
+	solana.MustPublicKeyFromBase58("DttWaMuVvTiduZRnguLF7jNxTgiMBZ1hyAumKUiL2KRL"),
+	solana.MustPublicKeyFromBase58("3AVi9Tg9Uo68tJfuvoKvqKNWKkC5wPdSSdeBnizKZ6jT"),
+}
+
+func isJitoTipAccount(account solana.PublicKey) bool {
+	for _, tip := range JitoTipAccounts {
+		if account.Equals(tip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fees are the lamports the transaction cost on top of what it swapped.
+type Fees struct {
+	// Network is the base fee of the signatures.
+	Network uint64
+	// Priority is the compute unit price times the compute unit limit.
+	Priority uint64
+	// Tip is what was sent to the Jito tip accounts.
+	Tip uint64
+
+	ComputeUnitLimit uint32
+	// ComputeUnitPrice is in micro-lamports per compute unit.
+	ComputeUnitPrice uint64
+}
+
+// Total returns the lamports of all the fees.
+func (f Fees) Total() uint64 {
+	return f.Network + f.Priority + f.Tip
+}
+
+// Fees returns the network and priority fees of the transaction and the
+// Jito tips paid in it.
+func (p *Parser) Fees() Fees {
+	var fees Fees
+	limitSet := false
+	instructions := 0
+	for _, instr := range p.txInfo.Message.Instructions {
+		if !p.allAccountKeys[instr.ProgramIDIndex].Equals(solana.ComputeBudget) {
+			instructions++
+			continue
+		}
+		switch {
+		case len(instr.Data) >= 5 && instr.Data[0] == computeBudgetSetComputeUnitLimit:
+			fees.ComputeUnitLimit = min(binary.LittleEndian.Uint32(instr.Data[1:5]), maxComputeUnitLimit)
+			limitSet = true
+		case len(instr.Data) >= 9 && instr.Data[0] == computeBudgetSetComputeUnitPrice:
+			fees.ComputeUnitPrice = binary.LittleEndian.Uint64(instr.Data[1:9])
+		}
+	}
+	if !limitSet {
+		fees.ComputeUnitLimit = uint32(min(instructions*defaultInstructionComputeUnits, maxComputeUnitLimit))
+	}
+
+	// micro-lamports rounded up to lamports
+	priority := new(big.Int).Mul(new(big.Int).SetUint64(fees.ComputeUnitPrice), big.NewInt(int64(fees.ComputeUnitLimit)))
+	priority.Add(priority, big.NewInt(999_999))
+	priority.Quo(priority, big.NewInt(1_000_000))
+	if priority.IsUint64() {
+		fees.Priority = min(priority.Uint64(), p.txMeta.Fee)
+	} else {
+		fees.Priority = p.txMeta.Fee
+	}
+	fees.Network = p.txMeta.Fee - fees.Priority
+
+	for _, tip := range p.tips {
+		fees.Tip += tip
+	}
+	return fees
+}
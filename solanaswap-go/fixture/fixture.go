@@ -0,0 +1,233 @@
+// Package fixture stores raw transactions on disk so the parser can be
+// regression tested without talking to an RPC node.
+//
+// A fixture is the JSON encoding of an rpc.GetTransactionResult saved as
+// testdata/<signature>.json. Its golden file, testdata/<signature>.golden.json,
+// holds the expected ParseTransaction / ProcessSwapData output.
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// FixturePath returns the fixture file of a signature inside dir.
+func FixturePath(dir, sig string) string {
+	return filepath.Join(dir, sig+".json")
+}
+
+// GoldenPath returns the golden file of a signature inside dir.
+func GoldenPath(dir, sig string) string {
+	return filepath.Join(dir, sig+".golden.json")
+}
+
+// LoadFixture reads a recorded rpc.GetTransactionResult.
+func LoadFixture(path string) (*rpc.GetTransactionResult, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFixture(path, raw)
+}
+
+func decodeFixture(path string, raw []byte) (*rpc.GetTransactionResult, error) {
+	var tx rpc.GetTransactionResult
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	if tx.Transaction == nil || tx.Meta == nil {
+		return nil, fmt.Errorf("fixture %s has no transaction or meta", path)
+	}
+	return &tx, nil
+}
+
+// Golden is the expected parser output of one transaction.
+//
+// Zero-valued fields are not asserted by Compare, which lets hand-written
+// goldens pin only the fields that matter for a regression.
+type Golden struct {
+	Signature string      `json:"signature"`
+	Legs      []GoldenLeg `json:"legs"`
+	Swap      *GoldenSwap `json:"swap,omitempty"`
+	SwapError string      `json:"swapError,omitempty"`
+}
+
+type GoldenLeg struct {
+	Type           string `json:"type"`
+	Protocol       string `json:"protocol,omitempty"`
+	InputMint      string `json:"inputMint,omitempty"`
+	InputAmount    uint64 `json:"inputAmount,omitempty"`
+	InputDecimals  uint8  `json:"inputDecimals,omitempty"`
+	OutputMint     string `json:"outputMint,omitempty"`
+	OutputAmount   uint64 `json:"outputAmount,omitempty"`
+	OutputDecimals uint8  `json:"outputDecimals,omitempty"`
+	Pool           string `json:"pool,omitempty"`
+	Router         string `json:"router,omitempty"`
+}
+
+type GoldenSwap struct {
+	TokenInMint      string   `json:"tokenInMint,omitempty"`
+	TokenInAmount    uint64   `json:"tokenInAmount,omitempty"`
+	TokenInDecimals  uint8    `json:"tokenInDecimals,omitempty"`
+	TokenOutMint     string   `json:"tokenOutMint,omitempty"`
+	TokenOutAmount   uint64   `json:"tokenOutAmount,omitempty"`
+	TokenOutDecimals uint8    `json:"tokenOutDecimals,omitempty"`
+	AMMs             []string `json:"amms,omitempty"`
+}
+
+// Replay feeds a fixture through NewParser -> ParseTransaction ->
+// ProcessSwapData and captures the result as a Golden.
+func Replay(sig string, tx *rpc.GetTransactionResult) (*Golden, error) {
+	parser, err := solanaswapgo.NewParser(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating parser: %w", err)
+	}
+	legs, err := parser.ParseTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing transaction: %w", err)
+	}
+
+	golden := &Golden{Signature: sig, Legs: []GoldenLeg{}}
+	for _, leg := range legs {
+		golden.Legs = append(golden.Legs, newGoldenLeg(leg))
+	}
+
+	swapInfo, _, err := parser.ProcessSwapData(legs)
+	if err != nil {
+		golden.SwapError = err.Error()
+		return golden, nil
+	}
+	golden.Swap = &GoldenSwap{
+		TokenInMint:      swapInfo.TokenInMint.String(),
+		TokenInAmount:    swapInfo.TokenInAmount,
+		TokenInDecimals:  swapInfo.TokenInDecimals,
+		TokenOutMint:     swapInfo.TokenOutMint.String(),
+		TokenOutAmount:   swapInfo.TokenOutAmount,
+		TokenOutDecimals: swapInfo.TokenOutDecimals,
+		AMMs:             swapInfo.AMMs,
+	}
+	return golden, nil
+}
+
+func newGoldenLeg(leg solanaswapgo.SwapData) GoldenLeg {
+	g := GoldenLeg{Type: string(leg.Type)}
+	if leg.Tx == nil {
+		return g
+	}
+	g.Protocol = leg.Tx.Protocol
+	g.InputMint = leg.Tx.InputMint.String()
+	g.InputAmount = leg.Tx.InputAmount
+	g.InputDecimals = leg.Tx.InputMintDecimals
+	g.OutputMint = leg.Tx.OutputMint.String()
+	g.OutputAmount = leg.Tx.OutputAmount
+	g.OutputDecimals = leg.Tx.OutputMintDecimals
+	if !leg.Tx.Pool.IsZero() {
+		g.Pool = leg.Tx.Pool.String()
+	}
+	if !leg.Tx.Router.IsZero() {
+		g.Router = leg.Tx.Router.String()
+	}
+	return g
+}
+
+// LoadGolden reads a golden file.
+func LoadGolden(path string) (*Golden, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var golden Golden
+	if err := json.Unmarshal(raw, &golden); err != nil {
+		return nil, fmt.Errorf("failed to decode golden %s: %w", path, err)
+	}
+	return &golden, nil
+}
+
+// WriteGolden writes a golden file in its canonical indented form.
+func WriteGolden(path string, golden *Golden) error {
+	return writeJSON(path, golden)
+}
+
+func writeJSON(path string, v interface{}) error {
+	raw, err := encodeJSON(v)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, raw)
+}
+
+func encodeJSON(v interface{}) ([]byte, error) {
+	raw, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(raw, '\n'), nil
+}
+
+func writeFile(path string, raw []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o644)
+}
+
+// Compare reports every difference between the expected and the actual
+// output. Zero-valued expected fields are skipped.
+func Compare(want, got *Golden) []string {
+	var diffs []string
+	if len(want.Legs) != len(got.Legs) {
+		diffs = append(diffs, fmt.Sprintf("leg count: want %d, got %d", len(want.Legs), len(got.Legs)))
+	}
+	for i := 0; i < len(want.Legs) && i < len(got.Legs); i++ {
+		w, g := want.Legs[i], got.Legs[i]
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d type", i), w.Type, g.Type)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d protocol", i), w.Protocol, g.Protocol)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d inputMint", i), w.InputMint, g.InputMint)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d inputAmount", i), w.InputAmount, g.InputAmount)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d inputDecimals", i), w.InputDecimals, g.InputDecimals)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d outputMint", i), w.OutputMint, g.OutputMint)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d outputAmount", i), w.OutputAmount, g.OutputAmount)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d outputDecimals", i), w.OutputDecimals, g.OutputDecimals)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d pool", i), w.Pool, g.Pool)
+		diffs = appendDiff(diffs, fmt.Sprintf("leg %d router", i), w.Router, g.Router)
+	}
+
+	diffs = appendDiff(diffs, "swapError", want.SwapError, got.SwapError)
+	if want.Swap == nil {
+		return diffs
+	}
+	if got.Swap == nil {
+		return append(diffs, "swap: want swap info, got none")
+	}
+	w, g := want.Swap, got.Swap
+	diffs = appendDiff(diffs, "swap tokenInMint", w.TokenInMint, g.TokenInMint)
+	diffs = appendDiff(diffs, "swap tokenInAmount", w.TokenInAmount, g.TokenInAmount)
+	diffs = appendDiff(diffs, "swap tokenInDecimals", w.TokenInDecimals, g.TokenInDecimals)
+	diffs = appendDiff(diffs, "swap tokenOutMint", w.TokenOutMint, g.TokenOutMint)
+	diffs = appendDiff(diffs, "swap tokenOutAmount", w.TokenOutAmount, g.TokenOutAmount)
+	diffs = appendDiff(diffs, "swap tokenOutDecimals", w.TokenOutDecimals, g.TokenOutDecimals)
+	if len(w.AMMs) > 0 {
+		diffs = appendDiff(diffs, "swap amms", fmt.Sprint(sorted(w.AMMs)), fmt.Sprint(sorted(g.AMMs)))
+	}
+	return diffs
+}
+
+func sorted(s []string) []string {
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out
+}
+
+func appendDiff[T comparable](diffs []string, field string, want, got T) []string {
+	var zero T
+	if want == zero || want == got {
+		return diffs
+	}
+	return append(diffs, fmt.Sprintf("%s: want %v, got %v", field, want, got))
+}
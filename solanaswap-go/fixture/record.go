@@ -0,0 +1,191 @@
+package fixture
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TransactionGetter is the subset of *rpc.Client used by Recorder.
+type TransactionGetter interface {
+	GetTransaction(ctx context.Context, txSig solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+}
+
+// Recorder fetches transactions from RPC and stores them as fixtures along
+// with a draft golden file built from the current parser output.
+type Recorder struct {
+	Client     TransactionGetter
+	Dir        string
+	Commitment rpc.CommitmentType
+
+	// OverwriteGolden replaces existing golden files. By default a golden
+	// that is already on disk is kept, since it may have been hand-edited.
+	OverwriteGolden bool
+
+	// AddCases appends recorded signatures to Dir/cases.json.
+	AddCases bool
+}
+
+func NewRecorder(client TransactionGetter, dir string) *Recorder {
+	return &Recorder{
+		Client:     client,
+		Dir:        dir,
+		Commitment: rpc.CommitmentConfirmed,
+		AddCases:   true,
+	}
+}
+
+// Record fetches one signature and writes its fixture and draft golden.
+// The golden is replayed from the fixture as it is stored, and nothing is
+// written unless the replay succeeds.
+func (r *Recorder) Record(ctx context.Context, sig string) (*Golden, error) {
+	txSig, err := solana.SignatureFromBase58(sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature %q: %w", sig, err)
+	}
+
+	var maxTxVersion uint64 = 0
+	tx, err := r.Client.GetTransaction(ctx, txSig, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     r.Commitment,
+		MaxSupportedTransactionVersion: &maxTxVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting tx %s: %w", sig, err)
+	}
+	if tx == nil || tx.Transaction == nil || tx.Meta == nil {
+		return nil, fmt.Errorf("tx %s not found", sig)
+	}
+
+	normalized, err := normalize(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error normalizing tx %s: %w", sig, err)
+	}
+	fixturePath := FixturePath(r.Dir, sig)
+	raw, err := encodeJSON(normalized)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := decodeFixture(fixturePath, raw)
+	if err != nil {
+		return nil, err
+	}
+	golden, err := Replay(sig, stored)
+	if err != nil {
+		return nil, fmt.Errorf("error replaying tx %s: %w", sig, err)
+	}
+
+	if err := writeFile(fixturePath, raw); err != nil {
+		return nil, err
+	}
+	goldenPath := GoldenPath(r.Dir, sig)
+	if _, err := os.Stat(goldenPath); r.OverwriteGolden || errors.Is(err, fs.ErrNotExist) {
+		if err := WriteGolden(goldenPath, golden); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.AddCases {
+		if err := AddCase(r.Dir, "recorded "+sig[:8], sig); err != nil {
+			return nil, err
+		}
+	}
+	return golden, nil
+}
+
+// fixtureFile is the on-disk layout of a fixture. The transaction is always
+// stored base64 encoded, whatever encoding the RPC node answered with.
+type fixtureFile struct {
+	Slot        uint64                  `json:"slot"`
+	BlockTime   *solana.UnixTimeSeconds `json:"blockTime"`
+	Transaction solana.Data             `json:"transaction"`
+	Meta        *rpc.TransactionMeta    `json:"meta"`
+	Version     rpc.TransactionVersion  `json:"version"`
+}
+
+func normalize(tx *rpc.GetTransactionResult) (*fixtureFile, error) {
+	decoded, err := tx.Transaction.GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+	if decoded == nil {
+		return nil, errors.New("empty transaction")
+	}
+	raw, err := decoded.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &fixtureFile{
+		Slot:        tx.Slot,
+		BlockTime:   tx.BlockTime,
+		Transaction: solana.Data{Content: raw, Encoding: solana.EncodingBase64},
+		Meta:        tx.Meta,
+		Version:     tx.Version,
+	}, nil
+}
+
+// Case is one entry of testdata/cases.json.
+type Case struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+}
+
+// LoadCases reads dir/cases.json. A missing file yields no cases.
+func LoadCases(dir string) ([]Case, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, "cases.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	if err := json.Unmarshal(raw, &cases); err != nil {
+		return nil, fmt.Errorf("failed to decode cases.json: %w", err)
+	}
+	return cases, nil
+}
+
+// AddCase appends a signature to dir/cases.json unless it is already listed.
+func AddCase(dir, name, sig string) error {
+	cases, err := LoadCases(dir)
+	if err != nil {
+		return err
+	}
+	for _, c := range cases {
+		if c.Signature == sig {
+			return nil
+		}
+	}
+	return writeJSON(filepath.Join(dir, "cases.json"), append(cases, Case{Name: name, Signature: sig}))
+}
+
+// ReadSignatures reads one signature per line. Blank lines and lines
+// starting with '#' are ignored, as is anything after the first field.
+func ReadSignatures(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sigs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sigs = append(sigs, strings.Fields(line)[0])
+	}
+	return sigs, scanner.Err()
+}
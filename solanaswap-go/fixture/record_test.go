@@ -0,0 +1,144 @@
+package fixture
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRPC serves getTransaction for a single signature.
+func fakeRPC(t *testing.T, sig solana.Signature, result interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "getTransaction", req.Method)
+
+		var reqSig string
+		require.NoError(t, json.Unmarshal(req.Params[0], &reqSig))
+		var opts map[string]interface{}
+		require.NoError(t, json.Unmarshal(req.Params[1], &opts))
+		require.Equal(t, float64(0), opts["maxSupportedTransactionVersion"])
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": nil}
+		if reqSig == sig.String() {
+			resp["result"] = result
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+}
+
+func TestRecorder_Record(t *testing.T) {
+	signer := solana.PublicKeyFromBytes(make([]byte, 32))
+	sig := solana.SignatureFromBytes(append(make([]byte, 63), 1))
+	tx := &solana.Transaction{
+		Signatures: []solana.Signature{sig},
+		Message: solana.Message{
+			AccountKeys: solana.PublicKeySlice{signer},
+			Header:      solana.MessageHeader{NumRequiredSignatures: 1},
+		},
+	}
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	server := fakeRPC(t, sig, map[string]interface{}{
+		"slot":        42,
+		"blockTime":   1_700_000_000,
+		"transaction": []string{base64.StdEncoding.EncodeToString(raw), "base64"},
+		"meta": map[string]interface{}{
+			"fee":               5000,
+			"preBalances":       []uint64{1_000_000},
+			"postBalances":      []uint64{995_000},
+			"innerInstructions": []interface{}{},
+			"preTokenBalances":  []interface{}{},
+			"postTokenBalances": []interface{}{},
+		},
+		"version": 0,
+	})
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder := NewRecorder(rpc.New(server.URL), dir)
+	golden, err := recorder.Record(context.Background(), sig.String())
+	require.NoError(t, err)
+	require.Empty(t, golden.Legs)
+
+	loaded, err := LoadFixture(FixturePath(dir, sig.String()))
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), loaded.Slot)
+	require.Equal(t, raw, loaded.Transaction.GetBinary())
+	require.Equal(t, uint64(5000), loaded.Meta.Fee)
+	replayed, err := Replay(sig.String(), loaded)
+	require.NoError(t, err)
+	require.Equal(t, golden, replayed)
+
+	written, err := LoadGolden(GoldenPath(dir, sig.String()))
+	require.NoError(t, err)
+	require.Equal(t, golden, written)
+
+	cases, err := LoadCases(dir)
+	require.NoError(t, err)
+	require.Equal(t, []Case{{Name: "recorded " + sig.String()[:8], Signature: sig.String()}}, cases)
+
+	// A second run keeps the hand-edited golden and does not duplicate the case.
+	require.NoError(t, os.WriteFile(GoldenPath(dir, sig.String()), []byte(`{"signature":"edited","legs":[]}`), 0o644))
+	_, err = recorder.Record(context.Background(), sig.String())
+	require.NoError(t, err)
+	written, err = LoadGolden(GoldenPath(dir, sig.String()))
+	require.NoError(t, err)
+	require.Equal(t, "edited", written.Signature)
+	cases, err = LoadCases(dir)
+	require.NoError(t, err)
+	require.Len(t, cases, 1)
+
+	_, err = recorder.Record(context.Background(), solana.SignatureFromBytes(make([]byte, 64)).String())
+	require.Error(t, err)
+}
+
+func TestRecorder_RecordReplayError(t *testing.T) {
+	signer := solana.PublicKeyFromBytes(make([]byte, 32))
+	sig := solana.SignatureFromBytes(append(make([]byte, 63), 2))
+	tx := &solana.Transaction{
+		Signatures: []solana.Signature{sig},
+		Message: solana.Message{
+			AccountKeys: solana.PublicKeySlice{signer},
+			Header:      solana.MessageHeader{NumRequiredSignatures: 1},
+		},
+	}
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	// inner instructions of an instruction the transaction does not have
+	server := fakeRPC(t, sig, map[string]interface{}{
+		"slot":        42,
+		"transaction": []string{base64.StdEncoding.EncodeToString(raw), "base64"},
+		"meta": map[string]interface{}{
+			"fee":               5000,
+			"preBalances":       []uint64{1_000_000},
+			"postBalances":      []uint64{995_000},
+			"innerInstructions": []interface{}{map[string]interface{}{"index": 3, "instructions": []interface{}{}}},
+			"preTokenBalances":  []interface{}{},
+			"postTokenBalances": []interface{}{},
+		},
+		"version": 0,
+	})
+	defer server.Close()
+
+	dir := t.TempDir()
+	_, err = NewRecorder(rpc.New(server.URL), dir).Record(context.Background(), sig.String())
+	require.Error(t, err)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,136 @@
+package solanaswapgo_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/require"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/franco-bianco/solanaswap-go/solanaswap-go/fixture"
+)
+
+// FuzzParseTransaction feeds arbitrary transaction bytes and meta JSON to the
+// parser, alone and as a block. Any input may be rejected with an error,
+// none may panic. The corpus is seeded from the synthetic cases and every
+// recorded fixture.
+//
+//	go test ./solanaswap-go -run '^$' -fuzz FuzzParseTransaction
+func FuzzParseTransaction(f *testing.F) {
+	addSeed := func(result *rpc.GetTransactionResult) {
+		meta, err := json.Marshal(result.Meta)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(result.Transaction.GetBinary(), meta)
+	}
+	for _, sc := range syntheticCases {
+		addSeed(sc.build(f).result())
+	}
+	paths, err := filepath.Glob(filepath.Join(testdataDir, "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".golden.json") || filepath.Base(path) == "cases.json" {
+			continue
+		}
+		tx, err := fixture.LoadFixture(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		addSeed(tx)
+	}
+
+	f.Fuzz(func(t *testing.T, txBytes, metaJSON []byte) {
+		var meta *rpc.TransactionMeta
+		if err := json.Unmarshal(metaJSON, &meta); err != nil {
+			return
+		}
+
+		results, err := solanaswapgo.ParseBlock(1, &rpc.GetBlockResult{
+			Transactions: []rpc.TransactionWithMeta{{
+				Transaction: rpc.DataBytesOrJSONFromBytes(txBytes),
+				Meta:        meta,
+			}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range results {
+			if result.Err == nil {
+				result.Parser.ProcessAllSwaps(result.Swaps)
+			}
+		}
+
+		tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(txBytes))
+		if err != nil {
+			return
+		}
+		parser, err := solanaswapgo.NewTransactionParserFromTransaction(tx, meta)
+		if err != nil {
+			return
+		}
+		swaps, err := parser.ParseTransaction()
+		if err != nil {
+			return
+		}
+		parser.ProcessSwapData(swaps)
+		parser.ProcessAllSwaps(swaps)
+	})
+}
+
+func TestParseTransaction_Malformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(t testing.TB) *txBuilder
+	}{
+		{"pumpswap buy with missing accounts", func(t testing.TB) *txBuilder {
+			b := buildPumpSwapBuy(t)
+			b.outer[0].Accounts = b.outer[0].Accounts[:4]
+			return b
+		}},
+		{"raydium v4 with empty data", func(t testing.TB) *txBuilder {
+			b := newTxBuilder(t, "raydium-v4-empty")
+			b.addOuter(solanaswapgo.RAYDIUM_V4_PROGRAM_ID, []solana.PublicKey{b.signer}, nil)
+			return b
+		}},
+		{"transfer of an unknown token account", func(t testing.TB) *txBuilder {
+			b := buildRaydiumCPMMSwap(t)
+			b.meta.PostTokenBalances = b.meta.PostTokenBalances[:1]
+			b.meta.PreTokenBalances = nil
+			return b
+		}},
+		{"short transfer data", func(t testing.TB) *txBuilder {
+			b := buildRaydiumCPMMSwap(t)
+			for i := range b.inner[0] {
+				b.inner[0][i].Data = b.inner[0][i].Data[:3]
+			}
+			return b
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := solanaswapgo.NewParser(tt.build(t).result())
+			require.NoError(t, err)
+			swaps, err := parser.ParseTransaction()
+			require.NoError(t, err)
+			parser.ProcessSwapData(swaps)
+		})
+	}
+
+	tx := buildRaydiumCPMMSwap(t).result()
+	decoded, err := tx.Transaction.GetTransaction()
+	require.NoError(t, err)
+	_, err = solanaswapgo.NewTransactionParserFromTransaction(decoded, nil)
+	require.ErrorIs(t, err, solanaswapgo.ErrMissingMeta)
+
+	decoded.Message.Instructions[0].Accounts[0] = 200
+	_, err = solanaswapgo.NewTransactionParserFromTransaction(decoded, tx.Meta)
+	require.ErrorIs(t, err, solanaswapgo.ErrAccountIndexOutOfRange)
+}
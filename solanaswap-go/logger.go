@@ -0,0 +1,39 @@
+package solanaswapgo
+
+import "log/slog"
+
+// Logger receives the parser's log lines. args are alternating key/value
+// pairs, as in log/slog. Every line carries the transaction signature, and
+// lines logged while a handler runs also carry the program, the outer and
+// inner instruction index and the handler name.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+	With(args ...any) Logger
+}
+
+// NewSlogLogger adapts a *slog.Logger. A nil logger uses slog.Default().
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debug(msg string, args ...any) { s.l.Debug(msg, args...) }
+func (s slogLogger) Warn(msg string, args ...any)  { s.l.Warn(msg, args...) }
+func (s slogLogger) Error(msg string, args ...any) { s.l.Error(msg, args...) }
+func (s slogLogger) With(args ...any) Logger       { return slogLogger{s.l.With(args...)} }
+
+// NopLogger discards everything. It is the default logger of a Parser.
+type NopLogger struct{}
+
+func (NopLogger) Debug(msg string, args ...any) {}
+func (NopLogger) Warn(msg string, args ...any)  {}
+func (NopLogger) Error(msg string, args ...any) {}
+func (NopLogger) With(args ...any) Logger       { return NopLogger{} }
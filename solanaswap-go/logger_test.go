@@ -0,0 +1,31 @@
+package solanaswapgo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	b := buildRaydiumCPMMSwap(t)
+	b.outer[0].Data = append(anchorDiscriminator("collect_fund_fee"), make([]byte, 16)...)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	parser, err := solanaswapgo.NewParser(b.result(), solanaswapgo.WithLogger(solanaswapgo.NewSlogLogger(logger)))
+	require.NoError(t, err)
+	_, err = parser.ParseTransaction()
+	require.NoError(t, err)
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes.SplitN(buf.Bytes(), []byte("\n"), 2)[0], &line))
+	require.Equal(t, b.signature(), line["signature"])
+	require.Equal(t, solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID.String(), line["program"])
+	require.Equal(t, float64(0), line["outer"])
+	require.Equal(t, float64(-1), line["inner"])
+	require.Equal(t, solanaswapgo.PROTOCOL_RAYDIUM, line["handler"])
+}
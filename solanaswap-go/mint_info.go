@@ -0,0 +1,191 @@
+package solanaswapgo
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// MintInfo is the metadata of a token mint.
+type MintInfo struct {
+	Mint         solana.PublicKey `json:"mint"`
+	Decimals     uint8            `json:"decimals"`
+	TokenProgram solana.PublicKey `json:"tokenProgram,omitempty"`
+	Symbol       string           `json:"symbol,omitempty"`
+	Extensions   MintExtensions   `json:"extensions,omitempty"`
+	TransferFee  *TransferFee     `json:"transferFee,omitempty"`
+}
+
+// MintInfoProvider looks up mints the transaction has no token balance for.
+// Mints it does not know are left out of the result.
+type MintInfoProvider interface {
+	MintInfo(ctx context.Context, mints []solana.PublicKey) (map[solana.PublicKey]MintInfo, error)
+}
+
+// StaticMintInfo is a fixed set of mints.
+type StaticMintInfo map[solana.PublicKey]MintInfo
+
+func (s StaticMintInfo) MintInfo(ctx context.Context, mints []solana.PublicKey) (map[solana.PublicKey]MintInfo, error) {
+	found := make(map[solana.PublicKey]MintInfo)
+	for _, mint := range mints {
+		if info, ok := s[mint]; ok {
+			found[mint] = info
+		}
+	}
+	return found, nil
+}
+
+// LoadMintInfoFile reads a JSON array of MintInfo.
+func LoadMintInfoFile(path string) (StaticMintInfo, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var infos []MintInfo
+	if err := json.Unmarshal(raw, &infos); err != nil {
+		return nil, fmt.Errorf("failed to decode mint info file %s: %w", path, err)
+	}
+	s := make(StaticMintInfo, len(infos))
+	for _, info := range infos {
+		s[info.Mint] = info
+	}
+	return s, nil
+}
+
+// LRUMintInfo keeps the most recently used mints in memory and asks Next for
+// the others.
+type LRUMintInfo struct {
+	Next MintInfoProvider
+
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[solana.PublicKey]*list.Element
+}
+
+// NewLRUMintInfo returns a cache of size mints in front of next, which may be
+// nil when the cache is only filled with Add.
+func NewLRUMintInfo(size int, next MintInfoProvider) *LRUMintInfo {
+	return &LRUMintInfo{
+		Next:  next,
+		size:  size,
+		order: list.New(),
+		items: make(map[solana.PublicKey]*list.Element),
+	}
+}
+
+func (c *LRUMintInfo) Add(info MintInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[info.Mint]; ok {
+		e.Value = info
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[info.Mint] = c.order.PushFront(info)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(MintInfo).Mint)
+	}
+}
+
+func (c *LRUMintInfo) MintInfo(ctx context.Context, mints []solana.PublicKey) (map[solana.PublicKey]MintInfo, error) {
+	found := make(map[solana.PublicKey]MintInfo)
+	var missing []solana.PublicKey
+
+	c.mu.Lock()
+	for _, mint := range mints {
+		if e, ok := c.items[mint]; ok {
+			c.order.MoveToFront(e)
+			found[mint] = e.Value.(MintInfo)
+		} else {
+			missing = append(missing, mint)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 || c.Next == nil {
+		return found, nil
+	}
+	fetched, err := c.Next.MintInfo(ctx, missing)
+	for mint, info := range fetched {
+		c.Add(info)
+		found[mint] = info
+	}
+	return found, err
+}
+
+// MultipleAccountsGetter is the subset of *rpc.Client used by RPCMintInfo.
+type MultipleAccountsGetter interface {
+	GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error)
+}
+
+// RPCMintInfo reads mint accounts with getMultipleAccounts. Put it behind an
+// LRUMintInfo, every call hits the RPC node.
+type RPCMintInfo struct {
+	Client     MultipleAccountsGetter
+	Commitment rpc.CommitmentType
+}
+
+func NewRPCMintInfo(client MultipleAccountsGetter) *RPCMintInfo {
+	return &RPCMintInfo{Client: client, Commitment: rpc.CommitmentConfirmed}
+}
+
+// getMultipleAccounts accepts at most 100 accounts per request.
+const maxMultipleAccounts = 100
+
+// Layout of the SPL token mint account. Token-2022 mints with extensions are
+// padded to the token account size and tagged with an account type byte.
+const (
+	mintDecimalsOffset = 44
+	mintAccountSize    = 82
+	tokenAccountSize   = 165
+	accountTypeMint    = 1
+)
+
+func isMintAccount(data []byte) bool {
+	return len(data) == mintAccountSize || len(data) > tokenAccountSize && data[tokenAccountSize] == accountTypeMint
+}
+
+func (r *RPCMintInfo) MintInfo(ctx context.Context, mints []solana.PublicKey) (map[solana.PublicKey]MintInfo, error) {
+	found := make(map[solana.PublicKey]MintInfo)
+	for start := 0; start < len(mints); start += maxMultipleAccounts {
+		batch := mints[start:min(start+maxMultipleAccounts, len(mints))]
+		out, err := r.Client.GetMultipleAccountsWithOpts(ctx, batch, &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: r.Commitment,
+		})
+		if err != nil {
+			return found, fmt.Errorf("failed to get mint accounts: %w", err)
+		}
+		for i, account := range out.Value {
+			if i >= len(batch) || account == nil || account.Data == nil {
+				continue
+			}
+			if !account.Owner.Equals(solana.TokenProgramID) && !account.Owner.Equals(solana.Token2022ProgramID) {
+				continue
+			}
+			data := account.Data.GetBinary()
+			if !isMintAccount(data) {
+				continue
+			}
+			info := MintInfo{
+				Mint:         batch[i],
+				Decimals:     data[mintDecimalsOffset],
+				TokenProgram: account.Owner,
+			}
+			if account.Owner.Equals(solana.Token2022ProgramID) {
+				info.Extensions, info.TransferFee = parseMintExtensions(data)
+			}
+			found[batch[i]] = info
+		}
+	}
+	return found, nil
+}
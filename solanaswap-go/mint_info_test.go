@@ -0,0 +1,199 @@
+package solanaswapgo_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAccounts struct {
+	accounts map[solana.PublicKey]*rpc.Account
+	calls    int
+}
+
+func (f *fakeAccounts) GetMultipleAccountsWithOpts(ctx context.Context, accounts []solana.PublicKey, opts *rpc.GetMultipleAccountsOpts) (*rpc.GetMultipleAccountsResult, error) {
+	f.calls++
+	out := &rpc.GetMultipleAccountsResult{}
+	for _, acc := range accounts {
+		out.Value = append(out.Value, f.accounts[acc])
+	}
+	return out, nil
+}
+
+func tokenProgramAccount(owner solana.PublicKey, data []byte) *rpc.Account {
+	return &rpc.Account{Owner: owner, Data: rpc.DataBytesOrJSONFromBytes(data)}
+}
+
+func TestRPCMintInfo(t *testing.T) {
+	mint := make([]byte, 82)
+	mint[44] = 5
+	mint2022 := make([]byte, 200)
+	mint2022[44] = 8
+	mint2022[165] = 1
+	tokenAccount := make([]byte, 165)
+
+	mintKey, mint2022Key := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	tokenAccountKey, missingKey := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	fake := &fakeAccounts{accounts: map[solana.PublicKey]*rpc.Account{
+		mintKey:         tokenProgramAccount(solana.TokenProgramID, mint),
+		mint2022Key:     tokenProgramAccount(solana.Token2022ProgramID, mint2022),
+		tokenAccountKey: tokenProgramAccount(solana.TokenProgramID, tokenAccount),
+	}}
+
+	infos, err := solanaswapgo.NewRPCMintInfo(fake).MintInfo(context.Background(), []solana.PublicKey{mintKey, mint2022Key, tokenAccountKey, missingKey})
+	require.NoError(t, err)
+	require.Equal(t, map[solana.PublicKey]solanaswapgo.MintInfo{
+		mintKey:     {Mint: mintKey, Decimals: 5, TokenProgram: solana.TokenProgramID},
+		mint2022Key: {Mint: mint2022Key, Decimals: 8, TokenProgram: solana.Token2022ProgramID},
+	}, infos)
+
+	many := make([]solana.PublicKey, 250)
+	for i := range many {
+		many[i] = mintKey
+	}
+	fake.calls = 0
+	_, err = solanaswapgo.NewRPCMintInfo(fake).MintInfo(context.Background(), many)
+	require.NoError(t, err)
+	require.Equal(t, 3, fake.calls)
+}
+
+func TestLRUMintInfo(t *testing.T) {
+	a, b, c := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	mint := make([]byte, 82)
+	fake := &fakeAccounts{accounts: map[solana.PublicKey]*rpc.Account{
+		a: tokenProgramAccount(solana.TokenProgramID, mint),
+		b: tokenProgramAccount(solana.TokenProgramID, mint),
+		c: tokenProgramAccount(solana.TokenProgramID, mint),
+	}}
+	cache := solanaswapgo.NewLRUMintInfo(2, solanaswapgo.NewRPCMintInfo(fake))
+	ctx := context.Background()
+
+	infos, err := cache.MintInfo(ctx, []solana.PublicKey{a, b})
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	_, err = cache.MintInfo(ctx, []solana.PublicKey{a, b})
+	require.NoError(t, err)
+	require.Equal(t, 1, fake.calls)
+
+	// c evicts b, the least recently used
+	_, err = cache.MintInfo(ctx, []solana.PublicKey{a})
+	require.NoError(t, err)
+	_, err = cache.MintInfo(ctx, []solana.PublicKey{c})
+	require.NoError(t, err)
+	_, err = cache.MintInfo(ctx, []solana.PublicKey{a, c})
+	require.NoError(t, err)
+	require.Equal(t, 2, fake.calls)
+	_, err = cache.MintInfo(ctx, []solana.PublicKey{b})
+	require.NoError(t, err)
+	require.Equal(t, 3, fake.calls)
+}
+
+func TestWithMintInfoProvider(t *testing.T) {
+	build := func() *txBuilder {
+		b := buildPumpSwapBuy(t)
+		var balances []rpc.TokenBalance
+		for _, balance := range b.meta.PostTokenBalances {
+			if !balance.Mint.Equals(syntheticMint) {
+				balances = append(balances, balance)
+			}
+		}
+		b.meta.PostTokenBalances = balances
+		return b
+	}
+
+	legs, err := parseWithConfig(t, build(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, uint8(0), legs[0].Tx.OutputMintDecimals)
+
+	path := filepath.Join(t.TempDir(), "mints.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"mint": "`+syntheticMint.String()+`", "decimals": 6, "symbol": "SYN"}]`), 0o644))
+	mints, err := solanaswapgo.LoadMintInfoFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "SYN", mints[syntheticMint].Symbol)
+
+	legs, err = parseWithConfig(t, build(), solanaswapgo.NewParserConfig(solanaswapgo.WithMintInfoProvider(mints)))
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, syntheticMint, legs[0].Tx.OutputMint)
+	require.Equal(t, uint8(6), legs[0].Tx.OutputMintDecimals)
+}
+
+// countingMintInfo records the calls made to it.
+type countingMintInfo struct {
+	solanaswapgo.StaticMintInfo
+	calls [][]solana.PublicKey
+	ctx   context.Context
+}
+
+func (c *countingMintInfo) MintInfo(ctx context.Context, mints []solana.PublicKey) (map[solana.PublicKey]solanaswapgo.MintInfo, error) {
+	c.calls = append(c.calls, mints)
+	c.ctx = ctx
+	return c.StaticMintInfo.MintInfo(ctx, mints)
+}
+
+func TestWithMintInfoProvider_Batched(t *testing.T) {
+	// the base mint has no balance, it is looked up in one call once the
+	// instructions are parsed, with the context of the config
+	b := buildPumpSwapBuy(t)
+	var balances []rpc.TokenBalance
+	for _, balance := range b.meta.PostTokenBalances {
+		if !balance.Mint.Equals(syntheticMint) {
+			balances = append(balances, balance)
+		}
+	}
+	b.meta.PostTokenBalances = balances
+
+	provider := &countingMintInfo{StaticMintInfo: solanaswapgo.StaticMintInfo{
+		syntheticMint: {Mint: syntheticMint, Decimals: 6},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig(
+		solanaswapgo.WithMintInfoProvider(provider), solanaswapgo.WithContext(ctx)))
+	require.NoError(t, err)
+	require.Empty(t, provider.calls)
+
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, uint8(6), legs[0].Tx.OutputMintDecimals)
+	require.Equal(t, [][]solana.PublicKey{{syntheticMint}}, provider.calls)
+	require.Equal(t, ctx, provider.ctx)
+
+	_, _, err = parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Len(t, provider.calls, 1)
+}
+
+func TestWithMintInfoProvider_JupiterEvents(t *testing.T) {
+	// route events carry no decimals and have no legs, the hops built from
+	// them get the decimals of the batched lookup
+	b, mintA, mintB, _ := buildSplitRoute(t)
+	sol := solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID
+	provider := &countingMintInfo{StaticMintInfo: solanaswapgo.StaticMintInfo{
+		sol:           {Mint: sol, Decimals: 9},
+		mintA:         {Mint: mintA, Decimals: 5},
+		mintB:         {Mint: mintB, Decimals: 2},
+		syntheticUSDC: {Mint: syntheticUSDC, Decimals: 6},
+	}}
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig(solanaswapgo.WithMintInfoProvider(provider)))
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, provider.calls, 1)
+
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Len(t, provider.calls, 1)
+	require.Equal(t, uint8(9), info.TokenInDecimals)
+	require.Equal(t, uint8(6), info.TokenOutDecimals)
+	require.Equal(t, uint8(5), info.Route.Hops[0].OutputDecimals)
+	require.Equal(t, uint8(2), info.Route.Hops[3].InputDecimals)
+}
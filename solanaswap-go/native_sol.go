@@ -0,0 +1,288 @@
+package solanaswapgo
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// System program instructions that move lamports, see
+// solana-program system_instruction.rs.
+const (
+	systemInstructionCreateAccount = 0
+	systemInstructionAssign        = 1
+	systemInstructionTransfer      = 2
+	systemInstructionAllocate      = 8
+)
+
+// Token program instructions of the wrapped SOL lifecycle.
+const (
+	tokenInstructionCloseAccount = 9
+	tokenInstructionSyncNative   = 17
+)
+
+// rentExemptMinimum is the balance an account of size bytes needs to be rent
+// exempt at the default rent of 3480 lamports per byte-year over two years.
+func rentExemptMinimum(size uint64) uint64 {
+	return (size + 128) * 3480 * 2
+}
+
+// RentLedger sums the lamports the owner of a transaction locked in new
+// accounts and got back from closed token accounts. They are kept out of
+// the swap amounts.
+type RentLedger struct {
+	Paid     uint64
+	Refunded uint64
+}
+
+// Net returns the lamports the owner got back minus the ones paid.
+func (r RentLedger) Net() int64 {
+	return int64(r.Refunded) - int64(r.Paid)
+}
+
+func systemInstruction(instr solana.CompiledInstruction) (uint32, bool) {
+	if len(instr.Data) < 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(instr.Data), true
+}
+
+// isSystemTransfer checks if the instruction is a System program transfer
+// of native SOL that can be a swap leg, wraps and rent are not
+func (p *Parser) isSystemTransfer(instr solana.CompiledInstruction) bool {
+	if !p.allAccountKeys[instr.ProgramIDIndex].Equals(solana.SystemProgramID) || len(instr.Accounts) < 2 || len(instr.Data) < 12 {
+		return false
+	}
+	if ix, _ := systemInstruction(instr); ix != systemInstructionTransfer {
+		return false
+	}
+	return !p.nonSwapLamports[p.allAccountKeys[instr.Accounts[1]]]
+}
+
+// processSystemTransfer reports a native SOL transfer like a token transfer
+// of NATIVE_SOL_MINT_PROGRAM_ID so that SOL and WSOL legs read the same.
+func (p *Parser) processSystemTransfer(instr solana.CompiledInstruction) *TransferData {
+	if len(instr.Data) < 12 || len(instr.Accounts) < 2 {
+		return nil
+	}
+	return &TransferData{
+		Info: TransferInfo{
+			Amount:      binary.LittleEndian.Uint64(instr.Data[4:12]),
+			Source:      p.allAccountKeys[instr.Accounts[0]].String(),
+			Destination: p.allAccountKeys[instr.Accounts[1]].String(),
+			Authority:   p.allAccountKeys[instr.Accounts[0]].String(),
+		},
+		Type:     "systemTransfer",
+		Mint:     NATIVE_SOL_MINT_PROGRAM_ID.String(),
+		Decimals: 9,
+	}
+}
+
+// extractNativeFlows sorts the lamport movements of the transaction that are
+// not swaps: wrapping SOL into WSOL accounts, funding new accounts and the
+// rent refunded by closing token accounts.
+func (p *Parser) extractNativeFlows() {
+	owner := p.owner()
+	p.nonSwapLamports = make(map[solana.PublicKey]bool)
+	p.tips = make(map[solana.PublicKey]uint64)
+	p.rent = RentLedger{}
+
+	wsolAccounts := make(map[solana.PublicKey]bool)
+	preWSOL := make(map[solana.PublicKey]uint64)
+	for _, balance := range p.txMeta.PreTokenBalances {
+		if balance.Mint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) && balance.UiTokenAmount != nil {
+			account := p.allAccountKeys[balance.AccountIndex]
+			wsolAccounts[account] = true
+			preWSOL[account], _ = strconv.ParseUint(balance.UiTokenAmount.Amount, 10, 64)
+		}
+	}
+	for _, balance := range p.txMeta.PostTokenBalances {
+		if balance.Mint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) {
+			wsolAccounts[p.allAccountKeys[balance.AccountIndex]] = true
+		}
+	}
+
+	var instructions []solana.CompiledInstruction
+	for i, instr := range p.txInfo.Message.Instructions {
+		instructions = append(instructions, instr)
+		instructions = append(instructions, p.getInnerInstructions(i)...)
+	}
+
+	// accounts given space by the transaction were funded for rent
+	allocated := make(map[solana.PublicKey]bool)
+	for _, instr := range instructions {
+		if len(instr.Accounts) < 1 {
+			continue
+		}
+		progID := p.allAccountKeys[instr.ProgramIDIndex]
+		if ix, _ := systemInstruction(instr); progID.Equals(solana.SystemProgramID) && (ix == systemInstructionAllocate || ix == systemInstructionAssign) {
+			allocated[p.allAccountKeys[instr.Accounts[0]]] = true
+		}
+		if isTokenProgram(progID) && len(instr.Data) > 0 && instr.Data[0] == tokenInstructionSyncNative {
+			wsolAccounts[p.allAccountKeys[instr.Accounts[0]]] = true
+		}
+	}
+
+	created := make(map[solana.PublicKey]uint64)
+	for _, instr := range instructions {
+		progID := p.allAccountKeys[instr.ProgramIDIndex]
+		switch {
+		case progID.Equals(solana.SystemProgramID) && len(instr.Accounts) >= 2:
+			ix, _ := systemInstruction(instr)
+			from, to := p.allAccountKeys[instr.Accounts[0]], p.allAccountKeys[instr.Accounts[1]]
+			switch {
+			case ix == systemInstructionCreateAccount && len(instr.Data) >= 20:
+				lamports := binary.LittleEndian.Uint64(instr.Data[4:12])
+				space := binary.LittleEndian.Uint64(instr.Data[12:20])
+				rent := min(lamports, rentExemptMinimum(space))
+				created[to] = rent
+				p.nonSwapLamports[to] = true
+				if from.Equals(owner) {
+					p.rent.Paid += rent
+				}
+			case ix == systemInstructionTransfer && len(instr.Data) >= 12:
+				switch {
+				case isJitoTipAccount(to):
+					p.nonSwapLamports[to] = true
+					p.tips[from] += binary.LittleEndian.Uint64(instr.Data[4:12])
+				case wsolAccounts[to]:
+					p.nonSwapLamports[to] = true
+				case allocated[to]:
+					p.nonSwapLamports[to] = true
+					lamports := binary.LittleEndian.Uint64(instr.Data[4:12])
+					created[to] += lamports
+					if from.Equals(owner) {
+						p.rent.Paid += lamports
+					}
+				}
+			}
+		case isTokenProgram(progID) && len(instr.Data) > 0 && instr.Data[0] == tokenInstructionCloseAccount && len(instr.Accounts) >= 2:
+			account, destination := p.allAccountKeys[instr.Accounts[0]], p.allAccountKeys[instr.Accounts[1]]
+			if !destination.Equals(owner) {
+				continue
+			}
+			if rent, ok := created[account]; ok {
+				p.rent.Refunded += rent
+			} else if i, ok := p.accountIndex(account); ok && i < len(p.txMeta.PreBalances) {
+				p.rent.Refunded += p.txMeta.PreBalances[i] - min(preWSOL[account], p.txMeta.PreBalances[i])
+			}
+		}
+	}
+}
+
+func (tx *TxInfo) hasNativeSOL() bool {
+	return tx.InputMint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) || tx.OutputMint.Equals(NATIVE_SOL_MINT_PROGRAM_ID)
+}
+
+// lamportBalance stands in for the token balance of a lamport vault with
+// space bytes of data, such as a bonding curve. The rent-exempt minimum is
+// not liquidity and is left out.
+func (p *Parser) lamportBalance(accountIndex uint16, space uint64) (*rpc.TokenBalance, bool) {
+	if int(accountIndex) >= len(p.txMeta.PostBalances) {
+		return nil, false
+	}
+	return &rpc.TokenBalance{
+		AccountIndex: accountIndex,
+		Mint:         NATIVE_SOL_MINT_PROGRAM_ID,
+		UiTokenAmount: &rpc.UiTokenAmount{
+			Amount:   strconv.FormatUint(lamportReserve(p.txMeta.PostBalances[accountIndex], space), 10),
+			Decimals: 9,
+		},
+	}, true
+}
+
+// lamportReserve returns the lamports of a vault above its rent-exempt
+// minimum.
+func lamportReserve(lamports, space uint64) uint64 {
+	return lamports - min(rentExemptMinimum(space), lamports)
+}
+
+func (p *Parser) accountIndex(account solana.PublicKey) (int, bool) {
+	i, ok := p.accountIndexes[account]
+	return i, ok
+}
+
+// Rent returns the rent the owner of the transaction paid and got back.
+func (p *Parser) Rent() RentLedger {
+	return p.rent
+}
+
+// NativeSOLChange returns how much SOL the account gained or lost through the
+// transaction, counting native SOL and the WSOL in its token accounts as one
+// asset and leaving out the transaction fee, its Jito tips and the rent of
+// the owner.
+func (p *Parser) NativeSOLChange(account solana.PublicKey) int64 {
+	var change int64
+	if i, ok := p.accountIndex(account); ok && i < len(p.txMeta.PreBalances) && i < len(p.txMeta.PostBalances) {
+		change = int64(p.txMeta.PostBalances[i]) - int64(p.txMeta.PreBalances[i])
+		if i == 0 {
+			change += int64(p.txMeta.Fee)
+		}
+	}
+	change += int64(p.tips[account])
+	if account.Equals(p.owner()) {
+		change -= p.rent.Net()
+	}
+
+	wsol := func(balances []rpc.TokenBalance) (total int64) {
+		for _, balance := range balances {
+			if balance.Mint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) && balance.Owner != nil && balance.Owner.Equals(account) && balance.UiTokenAmount != nil {
+				amount, _ := strconv.ParseInt(balance.UiTokenAmount.Amount, 10, 64)
+				total += amount
+			}
+		}
+		return total
+	}
+	return change + wsol(p.txMeta.PostTokenBalances) - wsol(p.txMeta.PreTokenBalances)
+}
+
+// legScope is the AMM instruction whose transfers are being scanned for a
+// leg, see isLegTransfer.
+type legScope struct {
+	// height is the stack height of the AMM instruction, 0 when unknown.
+	height int
+	vaults map[solana.PublicKey]bool
+}
+
+// newLegScope returns the scope of the AMM instruction ix of progID at
+// (outer, inner), inner is -1 for an outer instruction.
+func (p *Parser) newLegScope(progID solana.PublicKey, ix solana.CompiledInstruction, outer, inner int) legScope {
+	scope := legScope{height: p.stackHeight(outer, inner), vaults: make(map[solana.PublicKey]bool)}
+	descriptor, ok := p.config.ammRegistry.Lookup(progID)
+	if !ok {
+		return scope
+	}
+	layout, err := descriptor.match(ix.Data, len(ix.Accounts), p.config.kinds)
+	if err != nil {
+		return scope
+	}
+	for _, pos := range []int{layout.Accounts.PoolIn, layout.Accounts.PoolOut} {
+		if pos < 0 {
+			pos += len(ix.Accounts)
+		}
+		if pos >= 0 && pos < len(ix.Accounts) {
+			scope.vaults[p.allAccountKeys[ix.Accounts[pos]]] = true
+		}
+	}
+	return scope
+}
+
+// isLegTransfer checks if the instruction at (outer, inner) can be part of
+// the leg of the AMM of scope: token transfers, and System transfers that
+// move lamports in or out of its vaults. When the vaults are unknown, the
+// System transfers the AMM made itself are taken instead. SOL fees routers,
+// bots and the AMM itself take along the way are not part of the leg.
+func (p *Parser) isLegTransfer(instr solana.CompiledInstruction, outer, inner int, scope legScope) bool {
+	if p.isTransfer(instr) {
+		return true
+	}
+	if !p.isSystemTransfer(instr) {
+		return false
+	}
+	if len(scope.vaults) > 0 {
+		return scope.vaults[p.allAccountKeys[instr.Accounts[0]]] || scope.vaults[p.allAccountKeys[instr.Accounts[1]]]
+	}
+	return scope.height > 0 && p.stackHeight(outer, inner) == scope.height+1
+}
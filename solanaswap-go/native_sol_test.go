@@ -0,0 +1,274 @@
+package solanaswapgo_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+const tokenAccountRent = 2_039_280
+
+func systemTransferData(lamports uint64) []byte {
+	data := make([]byte, 12)
+	data[0] = 2
+	binary.LittleEndian.PutUint64(data[4:], lamports)
+	return data
+}
+
+func createAccountData(lamports, space uint64, owner solana.PublicKey) []byte {
+	data := make([]byte, 20, 52)
+	binary.LittleEndian.PutUint64(data[4:], lamports)
+	binary.LittleEndian.PutUint64(data[12:], space)
+	return append(data, owner[:]...)
+}
+
+// buildBondingCurveBuy buys a token from a curve holding native SOL, after
+// creating the token account of the buyer.
+func buildBondingCurveBuy(t testing.TB) (*txBuilder, solana.PublicKey) {
+	b := newTxBuilder(t, "bonding-curve-buy")
+	program := b.account("curve-program")
+	curve, curveVault := b.account("curve"), b.account("curve-vault")
+	userToken := b.account("user-token")
+
+	ata := b.addOuter(solana.SPLAssociatedTokenAccountProgramID, []solana.PublicKey{b.signer, userToken, b.signer, syntheticMint}, []byte{1})
+	b.addInner(ata, 2, solana.SystemProgramID, []solana.PublicKey{b.signer, userToken}, createAccountData(tokenAccountRent, 165, solana.TokenProgramID))
+
+	ix := b.addOuter(program, []solana.PublicKey{b.signer, curve, curveVault, userToken, solana.SystemProgramID},
+		append(anchorDiscriminator("swap"), make([]byte, 16)...))
+	b.addInner(ix, 2, solana.SystemProgramID, []solana.PublicKey{b.signer, curve}, systemTransferData(1_000_000_000))
+	b.addTransfer(ix, 2, curveVault, userToken, curve, 3_000_000_000_000)
+
+	b.tokenAccount(curveVault, syntheticMint, curve, 6, 800_000_000_000_000, 797_000_000_000_000)
+	b.tokenAccount(userToken, syntheticMint, b.signer, 6, 0, 3_000_000_000_000)
+	b.meta.PreTokenBalances = b.meta.PreTokenBalances[:1]
+	b.lamports(curve, 30_000_000_000, 31_000_000_000)
+	b.lamports(b.signer, 5_000_000_000, 5_000_000_000-5000-tokenAccountRent-1_000_000_000)
+	return b, program
+}
+
+func TestSystemTransferLeg(t *testing.T) {
+	b, program := buildBondingCurveBuy(t)
+	registry := solanaswapgo.NewAMMRegistry()
+	require.NoError(t, registry.Register(solanaswapgo.AMMDescriptor{
+		ProgramID:     program,
+		Protocol:      "Curve",
+		Accounts:      solanaswapgo.AMMAccounts{Pool: 1, PoolIn: 1, PoolOut: 2},
+		LamportVaults: []solanaswapgo.AMMLamportVault{{Account: 1}},
+	}))
+	config := solanaswapgo.NewParserConfig(
+		solanaswapgo.WithRegistry(registry),
+		solanaswapgo.WithHandlers(solanaswapgo.NewProtocolHandler("curve", solanaswapgo.RoleAMM, []solana.PublicKey{program},
+			func(ctx *solanaswapgo.ProtocolContext) []solanaswapgo.SwapData {
+				return ctx.ParseTransfers("Curve")
+			})),
+	)
+
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), config)
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+
+	tx := legs[0].Tx
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, tx.InputMint)
+	require.Equal(t, uint64(1_000_000_000), tx.InputAmount)
+	require.Equal(t, uint8(9), tx.InputMintDecimals)
+	require.Equal(t, syntheticMint, tx.OutputMint)
+	require.Equal(t, uint64(3_000_000_000_000), tx.OutputAmount)
+	// the rent-exempt minimum of the curve is not liquidity
+	require.Equal(t, "30999109120", tx.PoolInAmount.String())
+
+	require.Equal(t, solanaswapgo.RentLedger{Paid: tokenAccountRent}, parser.Rent())
+	require.Equal(t, int64(-1_000_000_000), parser.NativeSOLChange(b.signer))
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, parser.Rent(), info.Rent)
+}
+
+func TestLamportVaultNotMarked(t *testing.T) {
+	// without a lamport vault in the descriptor, the curve has no balance to
+	// read and the leg is dropped
+	b, program := buildBondingCurveBuy(t)
+	registry := solanaswapgo.NewAMMRegistry()
+	require.NoError(t, registry.Register(solanaswapgo.AMMDescriptor{
+		ProgramID: program,
+		Protocol:  "Curve",
+		Accounts:  solanaswapgo.AMMAccounts{Pool: 1, PoolIn: 1, PoolOut: 2},
+	}))
+	config := solanaswapgo.NewParserConfig(
+		solanaswapgo.WithRegistry(registry),
+		solanaswapgo.WithHandlers(solanaswapgo.NewProtocolHandler("curve", solanaswapgo.RoleAMM, []solana.PublicKey{program},
+			func(ctx *solanaswapgo.ProtocolContext) []solanaswapgo.SwapData {
+				return ctx.ParseTransfers("Curve")
+			})),
+	)
+
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), config)
+	require.NoError(t, err)
+	legs, diags, err := parser.ParseTransactionWithDiagnostics()
+	require.NoError(t, err)
+	require.Empty(t, legs)
+	require.ErrorIs(t, diags.Err(), solanaswapgo.ErrMissingPostBalance)
+}
+
+// buildPumpFunCurveBuy buys a token from a Pump.fun bonding curve, which
+// keeps its SOL reserve as the lamports of the curve account, paying the
+// protocol fee on the side.
+func buildPumpFunCurveBuy(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "pumpfun-curve-buy")
+	curve, curveToken := b.account("bonding-curve"), b.account("associated-bonding-curve")
+	userToken, feeRecipient := b.account("associated-user"), b.account("fee-recipient")
+
+	data := append(solanaswapgo.PumpFunAMMBuyDiscriminator[:], make([]byte, 16)...)
+	binary.LittleEndian.PutUint64(data[8:], 3_000_000_000_000)
+	binary.LittleEndian.PutUint64(data[16:], 1_050_000_000)
+	ix := b.addOuter(solanaswapgo.PUMP_FUN_PROGRAM_ID, []solana.PublicKey{
+		b.account("global"), feeRecipient, syntheticMint, curve, curveToken,
+		userToken, b.signer, solana.SystemProgramID, solana.TokenProgramID,
+	}, data)
+	b.addInner(ix, 2, solana.SystemProgramID, []solana.PublicKey{b.signer, curve}, systemTransferData(1_000_000_000))
+	b.addTransfer(ix, 2, curveToken, userToken, curve, 3_000_000_000_000)
+	b.addInner(ix, 2, solana.SystemProgramID, []solana.PublicKey{b.signer, feeRecipient}, systemTransferData(10_000_000))
+
+	b.tokenAccount(curveToken, syntheticMint, curve, 6, 800_000_000_000_000, 797_000_000_000_000)
+	b.tokenAccount(userToken, syntheticMint, b.signer, 6, 0, 3_000_000_000_000)
+	// 30 SOL above the rent-exempt minimum of the 81 bytes of the curve
+	b.lamports(curve, 30_001_454_640, 31_001_454_640)
+	b.lamports(feeRecipient, 0, 10_000_000)
+	b.lamports(b.signer, 5_000_000_000, 5_000_000_000-5000-1_000_000_000-10_000_000)
+	return b
+}
+
+func TestPumpFunCurveReserve(t *testing.T) {
+	// the built-in descriptor reads the SOL reserve of the curve from its
+	// lamports, less the rent-exempt minimum
+	b := buildPumpFunCurveBuy(t)
+	config := solanaswapgo.NewParserConfig(
+		solanaswapgo.WithHandlers(solanaswapgo.NewProtocolHandler("pumpfun-transfers", solanaswapgo.RoleAMM, []solana.PublicKey{solanaswapgo.PUMP_FUN_PROGRAM_ID},
+			func(ctx *solanaswapgo.ProtocolContext) []solanaswapgo.SwapData {
+				return ctx.ParseTransfers(solanaswapgo.PUMP_FUN)
+			})),
+	)
+
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), config)
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+
+	tx := legs[0].Tx
+	require.Equal(t, "PumpFun.AMM", tx.Protocol)
+	require.Equal(t, b.account("bonding-curve"), tx.Pool)
+	require.Equal(t, b.account("bonding-curve"), tx.PoolIn)
+	require.Equal(t, b.account("associated-bonding-curve"), tx.PoolOut)
+	// the protocol fee the curve sends to its fee recipient is not part of
+	// the leg
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, tx.InputMint)
+	require.Equal(t, uint64(1_000_000_000), tx.InputAmount)
+	require.Equal(t, syntheticMint, tx.OutputMint)
+	require.Equal(t, uint64(3_000_000_000_000), tx.OutputAmount)
+	require.Equal(t, "31000000000", tx.PoolInAmount.String())
+	require.Equal(t, "30000000000", tx.PoolInPreAmount.String())
+}
+
+// buildBotCurveBuy buys from a lamport curve through a trading bot that
+// takes a SOL fee right after the curve instruction, inside the transfers
+// scanned for the leg.
+func buildBotCurveBuy(t testing.TB) (*txBuilder, solana.PublicKey) {
+	b := newTxBuilder(t, "bot-curve-buy")
+	program := b.account("curve-program")
+	curve, curveVault := b.account("curve"), b.account("curve-vault")
+	userToken, botWallet := b.account("user-token"), b.account("bot-wallet")
+
+	ix := b.addOuter(solanaswapgo.BLOOM_PROGRAM_ID, []solana.PublicKey{b.signer}, []byte{1})
+	b.addInner(ix, 2, program, []solana.PublicKey{b.signer, curve, curveVault, userToken, solana.SystemProgramID},
+		append(anchorDiscriminator("swap"), make([]byte, 16)...))
+	b.addInner(ix, 3, solana.SystemProgramID, []solana.PublicKey{b.signer, curve}, systemTransferData(1_000_000_000))
+	b.addTransfer(ix, 3, curveVault, userToken, curve, 3_000_000_000_000)
+	b.addInner(ix, 2, solana.SystemProgramID, []solana.PublicKey{b.signer, botWallet}, systemTransferData(10_000_000))
+
+	b.tokenAccount(curveVault, syntheticMint, curve, 6, 800_000_000_000_000, 797_000_000_000_000)
+	b.tokenAccount(userToken, syntheticMint, b.signer, 6, 0, 3_000_000_000_000)
+	b.lamports(curve, 30_000_000_000, 31_000_000_000)
+	b.lamports(botWallet, 0, 10_000_000)
+	b.lamports(b.signer, 5_000_000_000, 5_000_000_000-5000-1_000_000_000-10_000_000)
+	return b, program
+}
+
+func TestSystemTransferBotFee(t *testing.T) {
+	b, program := buildBotCurveBuy(t)
+	registry := solanaswapgo.NewAMMRegistry()
+	require.NoError(t, registry.Register(solanaswapgo.AMMDescriptor{
+		ProgramID:     program,
+		Protocol:      "Curve",
+		Accounts:      solanaswapgo.AMMAccounts{Pool: 1, PoolIn: 1, PoolOut: 2},
+		LamportVaults: []solanaswapgo.AMMLamportVault{{Account: 1}},
+	}))
+	config := solanaswapgo.NewParserConfig(
+		solanaswapgo.WithRegistry(registry),
+		solanaswapgo.WithHandlers(solanaswapgo.NewProtocolHandler("curve", solanaswapgo.RoleAMM, []solana.PublicKey{program},
+			func(ctx *solanaswapgo.ProtocolContext) []solanaswapgo.SwapData {
+				return ctx.ParseTransfers("Curve")
+			})),
+	)
+
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), config)
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+
+	// the bot fee is neither made by the curve nor paid into it
+	tx := legs[0].Tx
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, tx.InputMint)
+	require.Equal(t, uint64(1_000_000_000), tx.InputAmount)
+	require.Equal(t, uint64(3_000_000_000_000), tx.OutputAmount)
+	require.Equal(t, []solanaswapgo.BotFee{
+		{Program: solanaswapgo.BLOOM_PROGRAM_ID, Recipient: b.account("bot-wallet"), Mint: solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, Amount: 10_000_000, Decimals: 9},
+	}, parser.Costs().BotFees)
+}
+
+// buildUnwrappingSell sells a token on Raydium CPMM into a temporary WSOL
+// account that is closed at the end, returning its rent with the proceeds.
+func buildUnwrappingSell(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "unwrapping-sell")
+	pool := b.account("pool")
+	authority := b.account("authority")
+	userIn, tempWSOL := b.account("user-token"), b.account("temp-wsol")
+	vaultIn, vaultOut := b.account("vault-token"), b.account("vault-wsol")
+
+	b.addOuter(solana.SystemProgramID, []solana.PublicKey{b.signer, tempWSOL}, createAccountData(tokenAccountRent, 165, solana.TokenProgramID))
+	b.addOuter(solana.TokenProgramID, []solana.PublicKey{tempWSOL, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, b.signer}, []byte{18})
+
+	ix := b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userIn, tempWSOL, vaultIn, vaultOut,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticMint, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(ix, 2, userIn, vaultIn, b.signer, 5_000_000_000_000)
+	b.addTransfer(ix, 2, vaultOut, tempWSOL, authority, 2_000_000_000)
+
+	b.addOuter(solana.TokenProgramID, []solana.PublicKey{tempWSOL, b.signer, b.signer}, []byte{9})
+
+	b.tokenAccount(userIn, syntheticMint, b.signer, 6, 5_000_000_000_000, 0)
+	b.tokenAccount(vaultIn, syntheticMint, authority, 6, 100_000_000_000_000, 105_000_000_000_000)
+	b.tokenAccount(vaultOut, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, authority, 9, 42_000_000_000, 40_000_000_000)
+	b.lamports(b.signer, 1_000_000_000, 1_000_000_000-5000+2_000_000_000)
+	return b
+}
+
+func TestNativeSOLChange(t *testing.T) {
+	b := buildUnwrappingSell(t)
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, legs[0].Tx.OutputMint)
+	require.Equal(t, uint64(2_000_000_000), legs[0].Tx.OutputAmount)
+
+	require.Equal(t, solanaswapgo.RentLedger{Paid: tokenAccountRent, Refunded: tokenAccountRent}, parser.Rent())
+	require.Equal(t, int64(2_000_000_000), parser.NativeSOLChange(b.signer))
+}
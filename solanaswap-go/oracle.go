@@ -0,0 +1,234 @@
+package solanaswapgo
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+)
+
+// PriceOracle returns the USD price of a whole token of mint at a time, nil
+// when it has none.
+type PriceOracle interface {
+	PriceUSD(ctx context.Context, mint solana.PublicKey, at time.Time) (*decimal.Decimal, error)
+}
+
+// swapObserver is an oracle that learns prices from the parsed legs, they
+// are shown to it before they are valued.
+type swapObserver interface {
+	Observe(legs []SwapData)
+}
+
+// StaticPrices is a fixed price per mint, whatever the time.
+type StaticPrices map[solana.PublicKey]decimal.Decimal
+
+func (s StaticPrices) PriceUSD(ctx context.Context, mint solana.PublicKey, at time.Time) (*decimal.Decimal, error) {
+	price, ok := s[mint]
+	if !ok {
+		return nil, nil
+	}
+	return &price, nil
+}
+
+// Stablecoins are worth one dollar to StablecoinOracle.
+var Stablecoins = []solana.PublicKey{USDC_MINT, USDT_MINT}
+
+func isStablecoin(mint solana.PublicKey) bool {
+	for _, stable := range Stablecoins {
+		if mint.Equals(stable) {
+			return true
+		}
+	}
+	return false
+}
+
+// StablecoinOracle prices the Stablecoins at one dollar.
+type StablecoinOracle struct{}
+
+func (StablecoinOracle) PriceUSD(ctx context.Context, mint solana.PublicKey, at time.Time) (*decimal.Decimal, error) {
+	if !isStablecoin(mint) {
+		return nil, nil
+	}
+	one := decimal.NewFromInt(1)
+	return &one, nil
+}
+
+// OracleChain asks its oracles in order and returns the first price.
+type OracleChain []PriceOracle
+
+func (c OracleChain) PriceUSD(ctx context.Context, mint solana.PublicKey, at time.Time) (*decimal.Decimal, error) {
+	for _, oracle := range c {
+		price, err := oracle.PriceUSD(ctx, mint, at)
+		if err != nil || price != nil {
+			return price, err
+		}
+	}
+	return nil, nil
+}
+
+func (c OracleChain) Observe(legs []SwapData) {
+	for _, oracle := range c {
+		if observer, ok := oracle.(swapObserver); ok {
+			observer.Observe(legs)
+		}
+	}
+}
+
+// pricePoint is a SOL/USD price seen at a time.
+type pricePoint struct {
+	at    time.Time
+	price decimal.Decimal
+}
+
+// DefaultPriceMaxAge is how long InStreamOracle uses a SOL price unless
+// WithMaxPriceAge says otherwise.
+const DefaultPriceMaxAge = 10 * time.Minute
+
+// InStreamOracle learns SOL/USD from the swaps between SOL and a stablecoin
+// that the parsers using it have seen, and prices the stablecoins at one
+// dollar, so that swaps can be valued without any outside source. It keeps
+// the last size prices and is safe for concurrent use.
+//
+// What it knows depends on the order it sees the swaps in: ParseBlock and
+// ParseTransactions show it the transactions in block order, parsers run
+// elsewhere at once show them in whatever order they finish.
+type InStreamOracle struct {
+	mu     sync.Mutex
+	size   int
+	maxAge time.Duration
+	points []pricePoint
+}
+
+// InStreamOption configures an InStreamOracle.
+type InStreamOption func(*InStreamOracle)
+
+// WithMaxPriceAge makes the oracle ignore prices seen more than age before
+// the time asked for, 0 uses them however old they are.
+func WithMaxPriceAge(age time.Duration) InStreamOption {
+	return func(o *InStreamOracle) {
+		o.maxAge = age
+	}
+}
+
+// NewInStreamOracle returns an oracle remembering up to size SOL prices
+// for DefaultPriceMaxAge, changed by opts.
+func NewInStreamOracle(size int, opts ...InStreamOption) *InStreamOracle {
+	o := &InStreamOracle{size: max(size, 1), maxAge: DefaultPriceMaxAge}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Observe learns the SOL price of the SOL↔stablecoin legs that succeeded.
+func (o *InStreamOracle) Observe(legs []SwapData) {
+	for _, leg := range legs {
+		tx := leg.Tx
+		if tx == nil || tx.Status == TxStatusFailed || tx.ExecutionPrice == nil || tx.ExecutionPrice.Sign() <= 0 {
+			continue
+		}
+		var price decimal.Decimal
+		switch {
+		case tx.InputMint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) && isStablecoin(tx.OutputMint):
+			price = *tx.ExecutionPrice
+		case isStablecoin(tx.InputMint) && tx.OutputMint.Equals(NATIVE_SOL_MINT_PROGRAM_ID):
+			price = decimal.NewFromInt(1).Div(*tx.ExecutionPrice)
+		default:
+			continue
+		}
+		o.add(pricePoint{at: tx.valuedAt(), price: price})
+	}
+}
+
+func (o *InStreamOracle) add(point pricePoint) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := sort.Search(len(o.points), func(i int) bool { return o.points[i].at.After(point.at) })
+	o.points = append(o.points, pricePoint{})
+	copy(o.points[i+1:], o.points[i:])
+	o.points[i] = point
+	if len(o.points) > o.size {
+		o.points = o.points[len(o.points)-o.size:]
+	}
+}
+
+// PriceUSD returns the last SOL price seen at or before at, nil when there
+// is none or it is older than the max age.
+func (o *InStreamOracle) PriceUSD(ctx context.Context, mint solana.PublicKey, at time.Time) (*decimal.Decimal, error) {
+	if isStablecoin(mint) {
+		return StablecoinOracle{}.PriceUSD(ctx, mint, at)
+	}
+	if !mint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) {
+		return nil, nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := sort.Search(len(o.points), func(i int) bool { return o.points[i].at.After(at) })
+	if i == 0 {
+		return nil, nil
+	}
+	point := o.points[i-1]
+	if o.maxAge > 0 && at.Sub(point.at) > o.maxAge {
+		return nil, nil
+	}
+	return &point.price, nil
+}
+
+// valuedAt is the time a leg is priced at: its block time, or the time its
+// AMM logged.
+func (tx *TxInfo) valuedAt() time.Time {
+	if !tx.BlockTime.IsZero() {
+		return tx.BlockTime
+	}
+	return tx.ProtocolTimestamp
+}
+
+// usdValue values amount of mint with the oracle of the config, nil when it
+// has no price.
+func (p *Parser) usdValue(mint solana.PublicKey, amount uint64, decimals uint8, at time.Time) *decimal.Decimal {
+	if mint.IsZero() {
+		return nil
+	}
+	price, err := p.config.oracle.PriceUSD(p.config.ctx, mint, at)
+	if err != nil {
+		p.log.Warn("failed to price mint", "mint", mint, "err", err)
+		return nil
+	}
+	if price == nil {
+		return nil
+	}
+	value := tokens(new(big.Int).SetUint64(amount), decimals).Mul(*price)
+	return &value
+}
+
+// valueLegs shows the legs to an oracle that learns from them and values
+// their amounts in USD.
+func (p *Parser) valueLegs(legs []SwapData) {
+	if p.config.oracle == nil || p.deferValues {
+		return
+	}
+	if observer, ok := p.config.oracle.(swapObserver); ok {
+		observer.Observe(legs)
+	}
+	for _, leg := range legs {
+		tx := leg.Tx
+		if tx == nil {
+			continue
+		}
+		tx.AmountInUSD = p.usdValue(tx.InputMint, tx.InputAmount, tx.InputMintDecimals, tx.valuedAt())
+		tx.AmountOutUSD = p.usdValue(tx.OutputMint, tx.OutputAmount, tx.OutputMintDecimals, tx.valuedAt())
+	}
+}
+
+// valueSwap values the amounts of the swap in USD, see enrich.
+func (p *Parser) valueSwap(s *SwapInfo) {
+	if p.config.oracle == nil {
+		return
+	}
+	s.AmountInUSD = p.usdValue(s.TokenInMint, s.TokenInAmount, s.TokenInDecimals, s.Timestamp)
+	s.AmountOutUSD = p.usdValue(s.TokenOutMint, s.TokenOutAmount, s.TokenOutDecimals, s.Timestamp)
+}
@@ -0,0 +1,146 @@
+package solanaswapgo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// buildSOLUSDCSwap sells 2 SOL for 300 USDC on Raydium CPMM.
+func buildSOLUSDCSwap(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "sol-usdc-swap")
+	pool := b.account("pool")
+	authority := b.account("authority")
+	userIn, userOut := b.account("user-wsol"), b.account("user-usdc")
+	vaultIn, vaultOut := b.account("vault-wsol"), b.account("vault-usdc")
+	sol := solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID
+
+	ix := b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userIn, userOut, vaultIn, vaultOut,
+		solana.TokenProgramID, solana.TokenProgramID, sol, syntheticUSDC, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(ix, 2, userIn, vaultIn, b.signer, 2_000_000_000)
+	b.addTransfer(ix, 2, vaultOut, userOut, authority, 300_000_000)
+
+	b.tokenAccount(userIn, sol, b.signer, 9, 2_000_000_000, 0)
+	b.tokenAccount(userOut, syntheticUSDC, b.signer, 6, 0, 300_000_000)
+	b.tokenAccount(vaultIn, sol, authority, 9, 1_000_000_000_000, 1_002_000_000_000)
+	b.tokenAccount(vaultOut, syntheticUSDC, authority, 6, 150_000_000_000, 149_700_000_000)
+	b.lamports(b.signer, 2_000_000_000, 1_999_995_000)
+	return b
+}
+
+func TestPriceOracle(t *testing.T) {
+	oracle := solanaswapgo.OracleChain{
+		solanaswapgo.StablecoinOracle{},
+		solanaswapgo.StaticPrices{syntheticMint: decimal.RequireFromString("0.0004")},
+	}
+	parser, err := solanaswapgo.NewParserWithConfig(buildRaydiumCPMMSwap(t).result(), solanaswapgo.NewParserConfig(solanaswapgo.WithPriceOracle(oracle)))
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	requireDecimal(t, "25", legs[0].Tx.AmountInUSD)
+	requireDecimal(t, "24.493827156", legs[0].Tx.AmountOutUSD)
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	requireDecimal(t, "25", info.AmountInUSD)
+	requireDecimal(t, "24.493827156", info.AmountOutUSD)
+
+	// without an oracle nothing is valued
+	legs, err = parseWithConfig(t, buildRaydiumCPMMSwap(t), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Nil(t, legs[0].Tx.AmountInUSD)
+}
+
+func TestInStreamOracle(t *testing.T) {
+	oracle := solanaswapgo.NewInStreamOracle(16)
+	price, err := oracle.PriceUSD(context.Background(), solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, time.Unix(1_730_000_000, 0))
+	require.NoError(t, err)
+	require.Nil(t, price)
+
+	config := solanaswapgo.NewParserConfig(solanaswapgo.WithPriceOracle(oracle))
+	legs, err := parseWithConfig(t, buildSOLUSDCSwap(t), config)
+	require.NoError(t, err)
+	requireDecimal(t, "300", legs[0].Tx.AmountInUSD)
+	requireDecimal(t, "300", legs[0].Tx.AmountOutUSD)
+
+	price, err = oracle.PriceUSD(context.Background(), solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, time.Unix(1_730_000_060, 0))
+	require.NoError(t, err)
+	requireDecimal(t, "150", price)
+
+	// a later buy paid in SOL is valued at the learned price
+	legs, err = parseWithConfig(t, buildPumpSwapBuy(t), config)
+	require.NoError(t, err)
+	requireDecimal(t, "150", legs[0].Tx.AmountInUSD)
+	require.Nil(t, legs[0].Tx.AmountOutUSD)
+}
+
+func TestInStreamOracle_MaxAge(t *testing.T) {
+	learn := func(oracle *solanaswapgo.InStreamOracle) {
+		_, err := parseWithConfig(t, buildSOLUSDCSwap(t), solanaswapgo.NewParserConfig(solanaswapgo.WithPriceOracle(oracle)))
+		require.NoError(t, err)
+	}
+	priceAt := func(oracle *solanaswapgo.InStreamOracle, at int64) *decimal.Decimal {
+		price, err := oracle.PriceUSD(context.Background(), solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, time.Unix(at, 0))
+		require.NoError(t, err)
+		return price
+	}
+
+	oracle := solanaswapgo.NewInStreamOracle(16)
+	learn(oracle)
+	// no price from the future
+	require.Nil(t, priceAt(oracle, 1_729_999_999))
+	requireDecimal(t, "150", priceAt(oracle, 1_730_000_000+600))
+	require.Nil(t, priceAt(oracle, 1_730_000_000+601))
+
+	oracle = solanaswapgo.NewInStreamOracle(16, solanaswapgo.WithMaxPriceAge(0))
+	learn(oracle)
+	requireDecimal(t, "150", priceAt(oracle, 1_730_000_000+7*24*3600))
+}
+
+func TestInStreamOracle_BlockOrder(t *testing.T) {
+	// the buy comes before the swap the price is learned from, it is never
+	// valued whichever worker finishes first
+	builders := []*txBuilder{buildPumpSwapBuy(t), buildSOLUSDCSwap(t), buildPumpSwapBuy(t)}
+	blockTime := solana.UnixTimeSeconds(1_730_000_000)
+	block := &rpc.GetBlockResult{BlockTime: &blockTime}
+	for _, b := range builders {
+		block.Transactions = append(block.Transactions, blockTransaction(t, b))
+	}
+
+	for range 20 {
+		oracle := solanaswapgo.NewInStreamOracle(16)
+		results, err := solanaswapgo.ParseBlock(300_000_000, block, solanaswapgo.NewParserConfig(
+			solanaswapgo.WithPriceOracle(oracle), solanaswapgo.WithWorkers(3)))
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		require.Nil(t, results[0].Swaps[0].Tx.AmountInUSD)
+		requireDecimal(t, "300", results[1].Swaps[0].Tx.AmountInUSD)
+		requireDecimal(t, "150", results[2].Swaps[0].Tx.AmountInUSD)
+	}
+}
+
+type contextKey struct{}
+
+// contextOracle remembers the context it was asked with.
+type contextOracle struct{ ctx *context.Context }
+
+func (o contextOracle) PriceUSD(ctx context.Context, mint solana.PublicKey, at time.Time) (*decimal.Decimal, error) {
+	*o.ctx = ctx
+	return nil, nil
+}
+
+func TestWithContext(t *testing.T) {
+	var got context.Context
+	ctx := context.WithValue(context.Background(), contextKey{}, "parse")
+	_, err := parseWithConfig(t, buildRaydiumCPMMSwap(t), solanaswapgo.NewParserConfig(
+		solanaswapgo.WithContext(ctx), solanaswapgo.WithPriceOracle(contextOracle{ctx: &got})))
+	require.NoError(t, err)
+	require.Equal(t, "parse", got.Value(contextKey{}))
+}
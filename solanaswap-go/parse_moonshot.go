@@ -3,7 +3,6 @@ package solanaswapgo
 import (
 	"bytes"
 	"fmt"
-	"strconv"
 
 	ag_binary "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
@@ -29,26 +28,22 @@ var (
 	MOONSHOT_SELL_INSTRUCTION = ag_binary.TypeID([8]byte{51, 230, 133, 164, 1, 127, 131, 173})
 )
 
-// processMoonshotSwaps processes all Moonshot swap instructions in the transaction
-func (p *Parser) processMoonshotSwaps() []SwapData {
-	var swaps []SwapData
-
-	for _, instruction := range p.txInfo.Message.Instructions {
-		if p.isMoonshotTrade(instruction) {
-			swapData, err := p.parseMoonshotTradeInstruction(instruction)
-			if err != nil {
-				continue
-			}
-			swaps = append(swaps, *swapData)
-		}
+// processMoonshotSwaps processes the Moonshot swap of an outer instruction
+func (p *Parser) processMoonshotSwaps(instructionIndex int) []SwapData {
+	instruction := p.txInfo.Message.Instructions[instructionIndex]
+	if !p.isMoonshotTrade(instruction) {
+		return nil
 	}
-
-	return swaps
+	swapData, err := p.parseMoonshotTradeInstruction(instruction)
+	if err != nil {
+		return nil
+	}
+	return []SwapData{*swapData}
 }
 
 // isMoonshotTrade checks if the instruction is a Moonshot trade
 func (p *Parser) isMoonshotTrade(instruction solana.CompiledInstruction) bool {
-	return p.txInfo.Message.AccountKeys[instruction.ProgramIDIndex].Equals(MOONSHOT_PROGRAM_ID) && len(instruction.Data) == 33 && len(instruction.Accounts) == 11
+	return p.allAccountKeys[instruction.ProgramIDIndex].Equals(MOONSHOT_PROGRAM_ID) && len(instruction.Data) == 33 && len(instruction.Accounts) == 11
 }
 
 // parseMoonshotTradeInstruction parses a Moonshot trade instruction
@@ -70,7 +65,7 @@ func (p *Parser) parseMoonshotTradeInstruction(instruction solana.CompiledInstru
 		return nil, fmt.Errorf("unknown moonshot trade instruction")
 	}
 
-	moonshotTokenMint := p.txInfo.Message.AccountKeys[instruction.Accounts[6]]
+	moonshotTokenMint := p.allAccountKeys[instruction.Accounts[6]]
 
 	moonshotTokenBalanceChanges, err := p.getTokenBalanceChanges(moonshotTokenMint)
 	if err != nil {
@@ -98,42 +93,20 @@ func (p *Parser) parseMoonshotTradeInstruction(instruction solana.CompiledInstru
 // getTokenBalanceChanges calculates the balance change for a given token mint for the signer
 func (p *Parser) getTokenBalanceChanges(mint solana.PublicKey) (int64, error) {
 	if mint == NATIVE_SOL_MINT_PROGRAM_ID {
-		// For native SOL, we'll use the first account (index 0) which is typically the fee payer/signer
+		// For native SOL, we'll use the first account (index 0) which is typically the fee payer/signer,
+		// without the fee and the rent of the accounts opened or closed by the trade
 		if len(p.txMeta.PostBalances) == 0 || len(p.txMeta.PreBalances) == 0 {
 			return 0, fmt.Errorf("insufficient balance information for SOL")
 		}
-		change := int64(p.txMeta.PostBalances[0]) - int64(p.txMeta.PreBalances[0])
-		return change, nil
+		return p.NativeSOLChange(p.allAccountKeys[0]), nil
 	}
 
 	// Get the signer's public key (assuming it's the first account in the transaction)
-	signer := p.txInfo.Message.AccountKeys[0]
-
-	var preAmount, postAmount int64
-	var balanceFound bool
-
-	for _, preBalance := range p.txMeta.PreTokenBalances {
-		if preBalance.Mint.Equals(mint) && preBalance.Owner.Equals(signer) {
-			preAmount, _ = strconv.ParseInt(preBalance.UiTokenAmount.Amount, 10, 64)
-			balanceFound = true
-			break
-		}
-	}
-
-	for _, postBalance := range p.txMeta.PostTokenBalances {
-		if postBalance.Mint.Equals(mint) && postBalance.Owner.Equals(signer) {
-			postAmount, _ = strconv.ParseInt(postBalance.UiTokenAmount.Amount, 10, 64)
-			balanceFound = true
-			break
-		}
-	}
-
-	if !balanceFound {
+	delta, ok := p.balanceDelta(p.allAccountKeys[0], mint)
+	if !ok {
 		return 0, fmt.Errorf("could not find balance for specified mint and signer")
 	}
-
-	change := postAmount - preAmount
-	return change, nil
+	return delta.Amount, nil
 }
 
 func abs(n int64) int64 {
@@ -36,22 +36,21 @@ type RaydiumInitLiquidity struct {
 }
 
 func (p *Parser) processRaydSwaps(router solana.PublicKey, instructionIndex int, innerIdx int, instruction *solana.CompiledInstruction, isInner bool) []SwapData {
-	if router.Equals(RAYDIUM_V4_PROGRAM_ID) && instruction.Data[0] == 1 && !isInner { // init liquidity
+	if router.Equals(RAYDIUM_V4_PROGRAM_ID) && len(instruction.Data) > 0 && instruction.Data[0] == 1 && !isInner { // init liquidity
 		decoder := ag_binary.NewBorshDecoder(instruction.Data[1:])
 		var data RaydiumInitLiquidity
 		if err := decoder.Decode(&data); err != nil {
 			return nil
 		}
-		if len(instruction.Accounts) < 11 {
+		if len(instruction.Accounts) < 12 {
 			return nil
 		}
 		tx := &TxInfo{
 			Type:               TxTypeAdd,
 			Router:             router,
 			Amm:                router,
-			Owner:              *p.txInfo.Message.Signers().Last(),
+			Owner:              p.owner(),
 			Protocol:           string(RAYDIUM),
-			Index:              uint(instructionIndex * 256),
 			InputMint:          p.allAccountKeys[instruction.Accounts[8]],
 			InputMintDecimals:  p.splTokenInfoMap[p.allAccountKeys[instruction.Accounts[10]].String()].Decimals,
 			InputAmount:        data.InitCoinAmount,
@@ -59,6 +58,7 @@ func (p *Parser) processRaydSwaps(router solana.PublicKey, instructionIndex int,
 			OutputMintDecimals: p.splTokenInfoMap[p.allAccountKeys[instruction.Accounts[11]].String()].Decimals,
 			OutputAmount:       data.InitPcAmount,
 		}
+		tx.setPosition(instructionIndex, -1)
 		if p.setTxPoolInfo(router, tx, p.txInfo.Message.Instructions[instructionIndex]) != nil {
 			return nil
 		}
@@ -79,13 +79,14 @@ func (p *Parser) processRaydSwaps(router solana.PublicKey, instructionIndex int,
 				tx := &TxInfo{}
 				tx.Router = router
 				tx.Amm = pID
-				tx.Owner = *p.txInfo.Message.Signers().Last()
-				tx.Index = uint(instructionIndex*256) + uint(i)
+				tx.Owner = p.owner()
+				tx.setPosition(instructionIndex, i)
 
+				scope := p.newLegScope(pID, innerInstruction, instructionIndex, i)
 				innerSwaps := []SwapData{}
-				for _, inner := range innerInstructions[i:] {
+				for j, inner := range innerInstructions[i:] {
 					switch {
-					case p.isTransfer(inner):
+					case p.isLegTransfer(inner, instructionIndex, i+j, scope):
 						transfer := p.processTransfer(inner)
 						if transfer != nil {
 							innerSwaps = append(innerSwaps, SwapData{Type: RAYDIUM, Data: transfer})
@@ -106,43 +107,43 @@ func (p *Parser) processRaydSwaps(router solana.PublicKey, instructionIndex int,
 					case *TransferData:
 						transfer := swap.Data.(*TransferData)
 						if i == 0 {
-							tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+							tx.InputMint = mintFromString(transfer.Mint)
 							tx.InputMintDecimals = transfer.Decimals
 							tx.InputAmount = transfer.Info.Amount
 							continue
 						}
 
-						if tx.InputMint.Equals(solana.MustPublicKeyFromBase58(transfer.Mint)) {
+						if tx.InputMint.Equals(mintFromString(transfer.Mint)) {
 							tx.InputAmount = transfer.Info.Amount
 							continue
 						}
 
-						tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+						tx.OutputMint = mintFromString(transfer.Mint)
 						tx.OutputMintDecimals = transfer.Decimals
 						tx.OutputAmount = transfer.Info.Amount
 					case *TransferCheck:
 						transfer := swap.Data.(*TransferCheck)
 						amount, _ := strconv.ParseFloat(transfer.Info.TokenAmount.Amount, 64)
 						if i == 0 {
-							tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+							tx.InputMint = mintFromString(transfer.Info.Mint)
 							tx.InputMintDecimals = transfer.Info.TokenAmount.Decimals
 							tx.InputAmount = uint64(amount)
 							continue
 						}
 
-						if tx.InputMint.Equals(solana.MustPublicKeyFromBase58(transfer.Info.Mint)) {
+						if tx.InputMint.Equals(mintFromString(transfer.Info.Mint)) {
 							tx.InputAmount = uint64(amount)
 							continue
 						}
 
-						tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+						tx.OutputMint = mintFromString(transfer.Info.Mint)
 						tx.OutputMintDecimals = transfer.Info.TokenAmount.Decimals
 						tx.OutputAmount = uint64(amount)
 					}
 				}
 
 				if err := p.setTxPoolInfo(pID, tx, innerInstruction); err != nil {
-					p.Log.Error(err)
+					p.log.Debug("failed to set pool info", "amm", pID, "err", err)
 					return swaps
 				}
 
@@ -156,6 +157,10 @@ func (p *Parser) processRaydSwaps(router solana.PublicKey, instructionIndex int,
 		return swaps
 	}
 
+	scope := p.newLegScope(router, p.txInfo.Message.Instructions[instructionIndex], instructionIndex, -1)
+	if isInner {
+		scope = p.newLegScope(router, *instruction, instructionIndex, innerIdx)
+	}
 	var swaps []SwapData
 	for _, innerInstructionSet := range p.txMeta.InnerInstructions {
 		if innerInstructionSet.Index == uint16(instructionIndex) {
@@ -169,13 +174,13 @@ func (p *Parser) processRaydSwaps(router solana.PublicKey, instructionIndex int,
 				}
 				// Stop if we encounter another known AMM (avoid mixing multi-leg transfers)
 				if isInner && i > innerIdx {
-					progID := p.allAccountKeys[p.convertRPCToSolanaInstruction(innerInstruction).ProgramIDIndex]
-					if p.isKnownAMM(progID) {
+					inner := p.convertRPCToSolanaInstruction(innerInstruction)
+					if p.isKnownAMM(p.allAccountKeys[inner.ProgramIDIndex], inner) {
 						break
 					}
 				}
 				switch {
-				case p.isTransfer(p.convertRPCToSolanaInstruction(innerInstruction)):
+				case p.isLegTransfer(p.convertRPCToSolanaInstruction(innerInstruction), instructionIndex, i, scope):
 					transfer := p.processTransfer(p.convertRPCToSolanaInstruction(innerInstruction))
 					if transfer != nil {
 						innerSwaps = append(innerSwaps, SwapData{Type: RAYDIUM, Data: transfer})
@@ -193,6 +198,9 @@ func (p *Parser) processRaydSwaps(router solana.PublicKey, instructionIndex int,
 			}
 			tx, err := p.parseTransferTxInfo(router, instructionIndex, RAYDIUM, innerSwaps, inst)
 			if err == nil {
+				if isInner {
+					tx.setPosition(instructionIndex, innerIdx)
+				}
 				swaps = append(swaps, SwapData{Type: RAYDIUM, Tx: tx})
 			}
 		}
@@ -203,22 +211,21 @@ func (p *Parser) parseTransferTxInfo(progId solana.PublicKey, instructionIndex i
 	tx = &TxInfo{
 		Router:   progId,
 		Amm:      progId,
-		Owner:    *p.txInfo.Message.Signers().Last(),
+		Owner:    p.owner(),
 		Protocol: string(protocal),
-		Index:    uint(instructionIndex * 256),
 	}
 	for i, swap := range swaps {
 		switch swap.Data.(type) {
 		case *TransferData:
 			transfer := swap.Data.(*TransferData)
 			if i == 0 {
-				tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+				tx.InputMint = mintFromString(transfer.Mint)
 				tx.InputMintDecimals = transfer.Decimals
 				tx.InputAmount = transfer.Info.Amount
 				continue
 			}
 
-			if tx.InputMint.Equals(solana.MustPublicKeyFromBase58(transfer.Mint)) {
+			if tx.InputMint.Equals(mintFromString(transfer.Mint)) {
 				tx.InputAmount = transfer.Info.Amount
 				continue
 			}
@@ -226,7 +233,7 @@ func (p *Parser) parseTransferTxInfo(progId solana.PublicKey, instructionIndex i
 			if !tx.OutputMint.IsZero() {
 				continue
 			}
-			tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+			tx.OutputMint = mintFromString(transfer.Mint)
 			tx.OutputMintDecimals = transfer.Decimals
 			tx.OutputAmount = transfer.Info.Amount
 
@@ -234,13 +241,13 @@ func (p *Parser) parseTransferTxInfo(progId solana.PublicKey, instructionIndex i
 			transfer := swap.Data.(*TransferCheck)
 			amount, _ := strconv.ParseFloat(transfer.Info.TokenAmount.Amount, 64)
 			if i == 0 {
-				tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+				tx.InputMint = mintFromString(transfer.Info.Mint)
 				tx.InputMintDecimals = transfer.Info.TokenAmount.Decimals
 				tx.InputAmount = uint64(amount)
 				continue
 			}
 
-			if tx.InputMint.Equals(solana.MustPublicKeyFromBase58(transfer.Info.Mint)) {
+			if tx.InputMint.Equals(mintFromString(transfer.Info.Mint)) {
 				tx.InputAmount = uint64(amount)
 				continue
 			}
@@ -248,7 +255,7 @@ func (p *Parser) parseTransferTxInfo(progId solana.PublicKey, instructionIndex i
 			if !tx.OutputMint.IsZero() {
 				continue
 			}
-			tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+			tx.OutputMint = mintFromString(transfer.Info.Mint)
 			tx.OutputMintDecimals = transfer.Info.TokenAmount.Decimals
 			tx.OutputAmount = uint64(amount)
 
@@ -284,82 +291,94 @@ func (p *Parser) processOrcaSwaps(instructionIndex int) []SwapData {
 }
 
 func (p *Parser) processHumidifiSwaps(instructionIndex int, innerIdx int, instruction *solana.CompiledInstruction) []SwapData {
+	swaps := p.processTransferSwaps(HUMIDIDI_PROGRAM_ID, HUMIDIDI, instructionIndex, innerIdx, instruction)
+	for _, swap := range swaps {
+		// HumidiFi emits output transfer (pool -> user) first, then input transfer
+		// (user -> pool). parseTransferTxInfo assumes first transfer is input,
+		// so we need to swap input/output for HumidiFi.
+		if !swap.Tx.OutputMint.IsZero() {
+			tx := swap.Tx
+			tx.InputMint, tx.OutputMint = tx.OutputMint, tx.InputMint
+			tx.InputAmount, tx.OutputAmount = tx.OutputAmount, tx.InputAmount
+			tx.InputMintDecimals, tx.OutputMintDecimals = tx.OutputMintDecimals, tx.InputMintDecimals
+		}
+	}
+	return swaps
+}
+
+// processTransferSwaps builds a leg of progID from the transfers following
+// the inner instruction innerIdx, or the outer instruction when it is -1, up
+// to the next known AMM.
+func (p *Parser) processTransferSwaps(progID solana.PublicKey, swapType SwapType, instructionIndex int, innerIdx int, instruction *solana.CompiledInstruction) []SwapData {
+	scope := p.newLegScope(progID, *instruction, instructionIndex, innerIdx)
+	start := max(innerIdx, 0)
 	var swaps []SwapData
 	for _, innerInstructionSet := range p.txMeta.InnerInstructions {
 		if innerInstructionSet.Index == uint16(instructionIndex) {
 			var innerSwaps []SwapData
 			for i, innerInstruction := range innerInstructionSet.Instructions {
-				if i < innerIdx {
+				if i < start {
 					continue
 				}
+				inner := p.convertRPCToSolanaInstruction(innerInstruction)
 				// Stop if we encounter another known AMM (avoid mixing multi-leg transfers)
-				if i > innerIdx {
-					progID := p.allAccountKeys[p.convertRPCToSolanaInstruction(innerInstruction).ProgramIDIndex]
-					if progID.Equals(PUMPFUN_AMM_PROGRAM_ID) ||
-						progID.Equals(RAYDIUM_V4_PROGRAM_ID) ||
-						progID.Equals(RAYDIUM_CPMM_PROGRAM_ID) ||
-						progID.Equals(RAYDIUM_CONCENTRATED_LIQUIDITY_PROGRAM_ID) ||
-						progID.Equals(ORCA_PROGRAM_ID) ||
-						progID.Equals(METEORA_PROGRAM_ID) ||
-						progID.Equals(METEORA_POOLS_PROGRAM_ID) ||
-						progID.Equals(METEORA_DLMM_PROGRAM_ID) ||
-						progID.Equals(METEORA_DAMM_V2) ||
-						progID.Equals(ZEROFI) {
-						break
-					}
+				if i > start && p.isKnownAMM(p.allAccountKeys[inner.ProgramIDIndex], inner) {
+					break
 				}
 				switch {
-				case p.isTransfer(p.convertRPCToSolanaInstruction(innerInstruction)):
-					transfer := p.processTransfer(p.convertRPCToSolanaInstruction(innerInstruction))
+				case p.isLegTransfer(inner, instructionIndex, i, scope):
+					transfer := p.processTransfer(inner)
 					if transfer != nil {
-						innerSwaps = append(innerSwaps, SwapData{Type: HUMIDIDI, Data: transfer})
+						innerSwaps = append(innerSwaps, SwapData{Type: swapType, Data: transfer})
 					}
-				case p.isTransferCheck(p.convertRPCToSolanaInstruction(innerInstruction)):
-					transfer := p.processTransferCheck(p.convertRPCToSolanaInstruction(innerInstruction))
+				case p.isTransferCheck(inner):
+					transfer := p.processTransferCheck(inner)
 					if transfer != nil {
-						innerSwaps = append(innerSwaps, SwapData{Type: HUMIDIDI, Data: transfer})
+						innerSwaps = append(innerSwaps, SwapData{Type: swapType, Data: transfer})
 					}
 				}
 			}
-			tx, err := p.parseTransferTxInfo(HUMIDIDI_PROGRAM_ID, instructionIndex, HUMIDIDI, innerSwaps, instruction)
+			tx, err := p.parseTransferTxInfo(progID, instructionIndex, swapType, innerSwaps, instruction)
 			if err == nil {
-				// HumidiFi emits output transfer (pool -> user) first, then input transfer
-				// (user -> pool). parseTransferTxInfo assumes first transfer is input,
-				// so we need to swap input/output for HumidiFi.
-				if len(innerSwaps) >= 2 {
-					tx.InputMint, tx.OutputMint = tx.OutputMint, tx.InputMint
-					tx.InputAmount, tx.OutputAmount = tx.OutputAmount, tx.InputAmount
-					tx.InputMintDecimals, tx.OutputMintDecimals = tx.OutputMintDecimals, tx.InputMintDecimals
-				}
-				swaps = append(swaps, SwapData{Type: HUMIDIDI, Tx: tx})
+				swaps = append(swaps, SwapData{Type: swapType, Tx: tx})
 			}
 		}
 	}
 	return swaps
 }
 
+// boundaryPrograms are AMMs without a handler that still end the transfers
+// of a leg.
+var boundaryPrograms = []solana.PublicKey{
+	PANCAKE_SWAP_PROGRAM_ID,
+	PHOENIX_PROGRAM_ID,
+}
+
 // isKnownAMM checks if a program ID is a known AMM / DEX program.
 // Used to stop scanning transfers when encountering another AMM in a router tx.
-func (p *Parser) isKnownAMM(progID solana.PublicKey) bool {
-	return progID.Equals(RAYDIUM_V4_PROGRAM_ID) ||
-		progID.Equals(RAYDIUM_CPMM_PROGRAM_ID) ||
-		progID.Equals(RAYDIUM_CONCENTRATED_LIQUIDITY_PROGRAM_ID) ||
-		progID.Equals(RAYDIUM_LAUNCHLAB_PROGRAM_ID) ||
-		progID.Equals(ORCA_PROGRAM_ID) ||
-		progID.Equals(METEORA_PROGRAM_ID) ||
-		progID.Equals(METEORA_POOLS_PROGRAM_ID) ||
-		progID.Equals(METEORA_DLMM_PROGRAM_ID) ||
-		progID.Equals(METEORA_DAMM_V2) ||
-		progID.Equals(Meteora_Dynamic_Bonding_Curve_Program) ||
-		progID.Equals(PUMPFUN_AMM_PROGRAM_ID) ||
-		progID.Equals(PUMP_FUN_PROGRAM_ID) ||
-		progID.Equals(ZEROFI) ||
-		progID.Equals(HUMIDIDI_PROGRAM_ID) ||
-		progID.Equals(PANCAKE_SWAP_PROGRAM_ID) ||
-		progID.Equals(PHOENIX_PROGRAM_ID)
+// Disabled protocols still count, so WithProtocols does not move the
+// boundary of the legs of the others.
+func (p *Parser) isKnownAMM(progID solana.PublicKey, ix solana.CompiledInstruction) bool {
+	for _, prog := range boundaryPrograms {
+		if progID.Equals(prog) {
+			return true
+		}
+	}
+	for _, h := range p.config.boundaries {
+		if h.Role() == RoleAMM && h.Match(progID, ix) {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *Parser) processTransfer(instr solana.CompiledInstruction) *TransferData {
+	if p.allAccountKeys[instr.ProgramIDIndex].Equals(solana.SystemProgramID) {
+		return p.processSystemTransfer(instr)
+	}
+	if len(instr.Data) < 9 || len(instr.Accounts) < 3 {
+		return nil
+	}
 	amount := binary.LittleEndian.Uint64(instr.Data[1:9])
 
 	transferData := &TransferData{
@@ -385,7 +404,7 @@ func (p *Parser) extractSPLTokenInfo() error {
 	splTokenAddresses := make(map[string]TokenInfo)
 
 	for _, accountInfo := range p.txMeta.PostTokenBalances {
-		if !accountInfo.Mint.IsZero() {
+		if !accountInfo.Mint.IsZero() && accountInfo.UiTokenAmount != nil {
 			accountKey := p.allAccountKeys[accountInfo.AccountIndex].String()
 			splTokenAddresses[accountKey] = TokenInfo{
 				Mint:     accountInfo.Mint.String(),
@@ -395,26 +414,31 @@ func (p *Parser) extractSPLTokenInfo() error {
 	}
 
 	processInstruction := func(instr solana.CompiledInstruction) {
-		if !p.allAccountKeys[instr.ProgramIDIndex].Equals(solana.TokenProgramID) {
+		if !isTokenProgram(p.allAccountKeys[instr.ProgramIDIndex]) || len(instr.Data) == 0 {
 			return
 		}
 
-		if len(instr.Data) == 0 || (instr.Data[0] != 3 && instr.Data[0] != 12) {
+		var source, destination string
+		info := TokenInfo{Mint: "", Decimals: 0}
+		switch transfer, checked := decodeCheckedTransfer(instr.Data); {
+		case instr.Data[0] == tokenInstructionTransfer && len(instr.Accounts) >= 3:
+			source = p.allAccountKeys[instr.Accounts[0]].String()
+			destination = p.allAccountKeys[instr.Accounts[1]].String()
+		case checked && len(instr.Accounts) >= 3:
+			// the checked transfers name their mint
+			source = p.allAccountKeys[instr.Accounts[0]].String()
+			destination = p.allAccountKeys[instr.Accounts[2]].String()
+			info.Mint = p.allAccountKeys[instr.Accounts[1]].String()
+			info.Decimals = transfer.decimals
+		default:
 			return
 		}
 
-		if len(instr.Accounts) < 3 {
-			return
-		}
-
-		source := p.allAccountKeys[instr.Accounts[0]].String()
-		destination := p.allAccountKeys[instr.Accounts[1]].String()
-
 		if _, exists := splTokenAddresses[source]; !exists {
-			splTokenAddresses[source] = TokenInfo{Mint: "", Decimals: 0}
+			splTokenAddresses[source] = info
 		}
 		if _, exists := splTokenAddresses[destination]; !exists {
-			splTokenAddresses[destination] = TokenInfo{Mint: "", Decimals: 0}
+			splTokenAddresses[destination] = info
 		}
 	}
 
@@ -448,17 +472,18 @@ func (p *Parser) processGeneralRouter(instructionIndex int) []SwapData {
 	var swaps []SwapData
 	for i, innerInstruction := range innerInstructions {
 		pID := p.allAccountKeys[innerInstruction.ProgramIDIndex]
-		if len(innerInstruction.Data) > 8 && swapDiscriminator[hex.EncodeToString(innerInstruction.Data[:8])] == true {
+		if len(innerInstruction.Data) > 8 && p.config.kinds.swap[hex.EncodeToString(innerInstruction.Data[:8])] == true {
 			tx := &TxInfo{}
 			tx.Router = router
 			tx.Amm = pID
-			tx.Owner = *p.txInfo.Message.Signers().Last()
-			tx.Index = uint(instructionIndex*256) + uint(i)
+			tx.Owner = p.owner()
+			tx.setPosition(instructionIndex, i)
 
+			scope := p.newLegScope(pID, innerInstruction, instructionIndex, i)
 			innerSwaps := []SwapData{}
-			for _, inner := range innerInstructions[i:] {
+			for j, inner := range innerInstructions[i:] {
 				switch {
-				case p.isTransfer(inner):
+				case p.isLegTransfer(inner, instructionIndex, i+j, scope):
 					transfer := p.processTransfer(inner)
 					if transfer != nil {
 						innerSwaps = append(innerSwaps, SwapData{Type: RAYDIUM, Data: transfer})
@@ -479,11 +504,11 @@ func (p *Parser) processGeneralRouter(instructionIndex int) []SwapData {
 				case *TransferData:
 					transfer := swap.Data.(*TransferData)
 					if i == 0 {
-						tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+						tx.InputMint = mintFromString(transfer.Mint)
 						tx.InputMintDecimals = transfer.Decimals
 						tx.InputAmount = transfer.Info.Amount
 					} else {
-						tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+						tx.OutputMint = mintFromString(transfer.Mint)
 						tx.OutputMintDecimals = transfer.Decimals
 						tx.OutputAmount = transfer.Info.Amount
 					}
@@ -491,11 +516,11 @@ func (p *Parser) processGeneralRouter(instructionIndex int) []SwapData {
 					transfer := swap.Data.(*TransferCheck)
 					amount, _ := strconv.ParseFloat(transfer.Info.TokenAmount.Amount, 64)
 					if i == 0 {
-						tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+						tx.InputMint = mintFromString(transfer.Info.Mint)
 						tx.InputMintDecimals = transfer.Info.TokenAmount.Decimals
 						tx.InputAmount = uint64(amount)
 					} else {
-						tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+						tx.OutputMint = mintFromString(transfer.Info.Mint)
 						tx.OutputMintDecimals = transfer.Info.TokenAmount.Decimals
 						tx.OutputAmount = uint64(amount)
 					}
@@ -503,7 +528,7 @@ func (p *Parser) processGeneralRouter(instructionIndex int) []SwapData {
 			}
 
 			if err := p.setTxPoolInfo(pID, tx, innerInstruction); err != nil {
-				p.Log.Error(err)
+				p.log.Debug("failed to set pool info", "amm", pID, "err", err)
 				return swaps
 			}
 
@@ -2,7 +2,6 @@ package solanaswapgo
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"math"
 	"strconv"
@@ -26,6 +25,8 @@ type TransferCheck struct {
 			UIAmount       float64 `json:"uiAmount"`
 			UIAmountString string  `json:"uiAmountString"`
 		} `json:"tokenAmount"`
+		// FeeAmount is withheld by transferCheckedWithFee.
+		FeeAmount uint64 `json:"feeAmount,omitempty"`
 	} `json:"info"`
 	Type string `json:"type"`
 }
@@ -47,10 +48,11 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 				inProgID := p.allAccountKeys[inner.ProgramIDIndex]
 				if progID.Equals(inProgID) && (bytes.Equal(discriminator, meteora_pools_program.Instruction_Swap[:]) ||
 					bytes.Equal(meteoradlmmprogram.Instruction_Swap2[:], discriminator) || bytes.Equal(meteora_damm_v2.Instruction_Swap[:], discriminator)) {
+				scope := p.newLegScope(progID, inner, outerIndex, innerIndex+i)
 				var innerSwaps []SwapData
-				for _, innerInstruction := range inners[i+1:] {
+				for j, innerInstruction := range inners[i+1:] {
 					inProgID2 := p.allAccountKeys[innerInstruction.ProgramIDIndex]
-					if !progID.Equals(inProgID2) && p.isKnownAMM(inProgID2) {
+					if !progID.Equals(inProgID2) && p.isKnownAMM(inProgID2, innerInstruction) {
 						break
 					}
 					switch {
@@ -59,7 +61,7 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 						if transfer != nil {
 							innerSwaps = append(innerSwaps, SwapData{Type: METEORA, Data: transfer})
 						}
-					case p.isTransfer(innerInstruction):
+					case p.isLegTransfer(innerInstruction, outerIndex, innerIndex+i+1+j, scope):
 						transfer := p.processTransfer(innerInstruction)
 						if transfer != nil {
 							innerSwaps = append(innerSwaps, SwapData{Type: METEORA, Data: transfer})
@@ -69,22 +71,22 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 					tx := &TxInfo{
 						Router:   router,
 						Amm:      progID,
-						Owner:    *p.txInfo.Message.Signers().Last(),
+						Owner:    p.owner(),
 						Protocol: string(METEORA),
-						Index:    uint(outerIndex * 256),
 					}
+					tx.setPosition(outerIndex, innerIndex+i)
 				for i, swap := range innerSwaps {
 					switch swap.Data.(type) {
 					case *TransferData:
 						transfer := swap.Data.(*TransferData)
 						if i == 0 {
-							tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+							tx.InputMint = mintFromString(transfer.Mint)
 							tx.InputMintDecimals = transfer.Decimals
 							tx.InputAmount = transfer.Info.Amount
 							continue
 						}
 
-						if tx.InputMint.Equals(solana.MustPublicKeyFromBase58(transfer.Mint)) {
+						if tx.InputMint.Equals(mintFromString(transfer.Mint)) {
 							tx.InputAmount = transfer.Info.Amount
 							continue
 						}
@@ -93,20 +95,20 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 							continue
 						}
 
-						tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+						tx.OutputMint = mintFromString(transfer.Mint)
 						tx.OutputMintDecimals = transfer.Decimals
 						tx.OutputAmount = transfer.Info.Amount
 					case *TransferCheck:
 						transfer := swap.Data.(*TransferCheck)
 						amount, _ := strconv.ParseFloat(transfer.Info.TokenAmount.Amount, 64)
 						if i == 0 {
-							tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+							tx.InputMint = mintFromString(transfer.Info.Mint)
 							tx.InputMintDecimals = transfer.Info.TokenAmount.Decimals
 							tx.InputAmount = uint64(amount)
 							continue
 						}
 
-						if tx.InputMint.Equals(solana.MustPublicKeyFromBase58(transfer.Info.Mint)) {
+						if tx.InputMint.Equals(mintFromString(transfer.Info.Mint)) {
 							tx.InputAmount = uint64(amount)
 							continue
 						}
@@ -115,7 +117,7 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 							continue
 						}
 
-						tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+						tx.OutputMint = mintFromString(transfer.Info.Mint)
 						tx.OutputMintDecimals = transfer.Info.TokenAmount.Decimals
 						tx.OutputAmount = uint64(amount)
 					}
@@ -123,9 +125,12 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 					err := p.setTxPoolInfo(progID, tx, inner)
 					// tx, err := p.parseTransferTxInfo(progID, outerIndex, METEORA, innerSwaps)
 					if err != nil {
-						p.Log.Errorf("failed to parse tx info: %v, program: %s, signatures: %v", err, progID, p.txInfo.Signatures)
+						p.log.Debug("failed to parse meteora tx info", "err", err)
 						return nil
 					}
+					if progID.Equals(METEORA_DAMM_V2) {
+						p.setDAMMv2Timestamp(tx, inners[i+1:])
+					}
 					return []SwapData{
 						{
 							Type: METEORA,
@@ -149,15 +154,16 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 			discriminator := outerInstriction.Data[:8]
 			if bytes.Equal(discriminator, meteora_pools_program.Instruction_Swap[:]) || bytes.Equal(meteoradlmmprogram.Instruction_Swap2[:], discriminator) ||
 				bytes.Equal(meteora_damm_v2.Instruction_Swap[:], discriminator) {
+				scope := p.newLegScope(progID, outerInstriction, outerIndex, -1)
 				var innerSwaps []SwapData
-				for _, innerInstruction := range inners {
+				for j, innerInstruction := range inners {
 					switch {
 					case p.isTransferCheck(innerInstruction):
 						transfer := p.processTransferCheck(innerInstruction)
 						if transfer != nil {
 							innerSwaps = append(innerSwaps, SwapData{Type: METEORA, Data: transfer})
 						}
-					case p.isTransfer(innerInstruction):
+					case p.isLegTransfer(innerInstruction, outerIndex, j, scope):
 						transfer := p.processTransfer(innerInstruction)
 						if transfer != nil {
 							innerSwaps = append(innerSwaps, SwapData{Type: METEORA, Data: transfer})
@@ -169,9 +175,12 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 				}
 				tx, err := p.parseTransferTxInfo(progID, outerIndex, METEORA, innerSwaps, nil)
 				if err != nil {
-					p.Log.Errorf("failed to parse tx info: %v, program: %s, signatures: %v", err, progID, p.txInfo.Signatures)
+					p.log.Debug("failed to parse meteora tx info", "err", err)
 					return nil
 				}
+				if progID.Equals(METEORA_DAMM_V2) {
+					p.setDAMMv2Timestamp(tx, inners)
+				}
 				return []SwapData{
 					{
 						Type: METEORA,
@@ -185,22 +194,33 @@ func (p *Parser) processMeteoraSwaps(progID solana.PublicKey, outerIndex int, in
 }
 
 func (p *Parser) processTransferCheck(instr solana.CompiledInstruction) *TransferCheck {
-	amount := binary.LittleEndian.Uint64(instr.Data[1:9])
+	transfer, ok := decodeCheckedTransfer(instr.Data)
+	if !ok || len(instr.Accounts) < 4 {
+		return nil
+	}
 
 	transferData := &TransferCheck{
 		Type: "transferChecked",
 	}
+	if transfer.withFee {
+		transferData.Type = "transferCheckedWithFee"
+	}
 
 	transferData.Info.Source = p.allAccountKeys[instr.Accounts[0]].String()
 	transferData.Info.Destination = p.allAccountKeys[instr.Accounts[2]].String()
 	transferData.Info.Mint = p.allAccountKeys[instr.Accounts[1]].String()
 	transferData.Info.Authority = p.allAccountKeys[instr.Accounts[3]].String()
 
-	transferData.Info.TokenAmount.Amount = fmt.Sprintf("%d", amount)
-	transferData.Info.TokenAmount.Decimals = p.splDecimalsMap[transferData.Info.Mint]
-	uiAmount := float64(amount) / math.Pow10(int(transferData.Info.TokenAmount.Decimals))
+	transferData.Info.TokenAmount.Amount = fmt.Sprintf("%d", transfer.amount)
+	if transfer.hasDecimals {
+		transferData.Info.TokenAmount.Decimals = transfer.decimals
+	} else {
+		transferData.Info.TokenAmount.Decimals = p.splDecimalsMap[transferData.Info.Mint]
+	}
+	uiAmount := float64(transfer.amount) / math.Pow10(int(transferData.Info.TokenAmount.Decimals))
 	transferData.Info.TokenAmount.UIAmount = uiAmount
 	transferData.Info.TokenAmount.UIAmountString = strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.9f", uiAmount), "0"), ".")
+	transferData.Info.FeeAmount = transfer.fee
 
 	return transferData
 }
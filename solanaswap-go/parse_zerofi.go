@@ -2,8 +2,6 @@ package solanaswapgo
 
 import (
 	"strconv"
-
-	"github.com/gagliardetto/solana-go"
 )
 
 func (p *Parser) processZerofiSwaps(instructionIndex int, isInner bool) []SwapData {
@@ -41,21 +39,21 @@ func (p *Parser) processZerofiSwaps(instructionIndex int, isInner bool) []SwapDa
 			tx := &TxInfo{
 				Router:   router,
 				Amm:      ZEROFI,
-				Owner:    *p.txInfo.Message.Signers().Last(),
+				Owner:    p.owner(),
 				Protocol: "ZeroFi",
-				Index:    uint(instructionIndex*256) + uint(i),
 			}
+			tx.setPosition(instructionIndex, i)
 
 			for j, swap := range innerSwaps {
 				switch swap.Data.(type) {
 				case *TransferData:
 					transfer := swap.Data.(*TransferData)
 					if j == 0 {
-						tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+						tx.InputMint = mintFromString(transfer.Mint)
 						tx.InputMintDecimals = transfer.Decimals
 						tx.InputAmount = transfer.Info.Amount
 					} else {
-						tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+						tx.OutputMint = mintFromString(transfer.Mint)
 						tx.OutputMintDecimals = transfer.Decimals
 						tx.OutputAmount = transfer.Info.Amount
 					}
@@ -63,11 +61,11 @@ func (p *Parser) processZerofiSwaps(instructionIndex int, isInner bool) []SwapDa
 					transfer := swap.Data.(*TransferCheck)
 					amount, _ := strconv.ParseFloat(transfer.Info.TokenAmount.Amount, 64)
 					if j == 0 {
-						tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+						tx.InputMint = mintFromString(transfer.Info.Mint)
 						tx.InputMintDecimals = transfer.Info.TokenAmount.Decimals
 						tx.InputAmount = uint64(amount)
 					} else {
-						tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+						tx.OutputMint = mintFromString(transfer.Info.Mint)
 						tx.OutputMintDecimals = transfer.Info.TokenAmount.Decimals
 						tx.OutputAmount = uint64(amount)
 					}
@@ -75,7 +73,7 @@ func (p *Parser) processZerofiSwaps(instructionIndex int, isInner bool) []SwapDa
 			}
 
 			if err := p.setTxPoolInfo(ZEROFI, tx, inner); err != nil {
-				p.Log.Errorf("failed to parse ZeroFi tx info: %v, signatures: %v", err, p.txInfo.Signatures)
+				p.log.Debug("failed to parse zerofi tx info", "err", err)
 				continue
 			}
 
@@ -113,21 +111,21 @@ func (p *Parser) processZerofiSwaps(instructionIndex int, isInner bool) []SwapDa
 	tx := &TxInfo{
 		Router:   ZEROFI,
 		Amm:      ZEROFI,
-		Owner:    *p.txInfo.Message.Signers().Last(),
+		Owner:    p.owner(),
 		Protocol: "ZeroFi",
-		Index:    uint(instructionIndex * 256),
 	}
+	tx.setPosition(instructionIndex, -1)
 
 	for j, swap := range innerSwaps {
 		switch swap.Data.(type) {
 		case *TransferData:
 			transfer := swap.Data.(*TransferData)
 			if j == 0 {
-				tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+				tx.InputMint = mintFromString(transfer.Mint)
 				tx.InputMintDecimals = transfer.Decimals
 				tx.InputAmount = transfer.Info.Amount
 			} else {
-				tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Mint)
+				tx.OutputMint = mintFromString(transfer.Mint)
 				tx.OutputMintDecimals = transfer.Decimals
 				tx.OutputAmount = transfer.Info.Amount
 			}
@@ -135,11 +133,11 @@ func (p *Parser) processZerofiSwaps(instructionIndex int, isInner bool) []SwapDa
 			transfer := swap.Data.(*TransferCheck)
 			amount, _ := strconv.ParseFloat(transfer.Info.TokenAmount.Amount, 64)
 			if j == 0 {
-				tx.InputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+				tx.InputMint = mintFromString(transfer.Info.Mint)
 				tx.InputMintDecimals = transfer.Info.TokenAmount.Decimals
 				tx.InputAmount = uint64(amount)
 			} else {
-				tx.OutputMint = solana.MustPublicKeyFromBase58(transfer.Info.Mint)
+				tx.OutputMint = mintFromString(transfer.Info.Mint)
 				tx.OutputMintDecimals = transfer.Info.TokenAmount.Decimals
 				tx.OutputAmount = uint64(amount)
 			}
@@ -147,7 +145,7 @@ func (p *Parser) processZerofiSwaps(instructionIndex int, isInner bool) []SwapDa
 	}
 
 	if err := p.setTxPoolInfo(ZEROFI, tx, outerInstr); err != nil {
-		p.Log.Errorf("failed to parse ZeroFi tx info: %v, signatures: %v", err, p.txInfo.Signatures)
+		p.log.Debug("failed to parse zerofi tx info", "err", err)
 		return nil
 	}
 
@@ -3,9 +3,7 @@ package solanaswapgo
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"errors"
 	"fmt"
-	"log"
 	"math/big"
 	"strconv"
 	"time"
@@ -13,7 +11,6 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/shopspring/decimal"
-	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -25,6 +22,8 @@ const (
 
 // routerPrograms 包含所有只需要委托 inner-instruction 解析的 router 程序。
 // 新增此类 router 时只需把 Program ID 加进这个 slice，无需修改 ParseTransaction 的 switch。
+// 不修改源码时可以用 WithRouterPrograms 追加。它只用来初始化 NewParserConfig，
+// 之后修改不会影响已有的 ParserConfig。
 var routerPrograms = []solana.PublicKey{
 	BANANA_GUN_PROGRAM_ID,
 	MINTECH_PROGRAM_ID,
@@ -43,15 +42,6 @@ var routerPrograms = []solana.PublicKey{
 	solana.MustPublicKeyFromBase58("AP51WLiiqTdbZfgyRMs35PsZpdmLuPDdHYmrB23pEtMU"),
 }
 
-func isRouterProgram(progID solana.PublicKey) bool {
-	for _, r := range routerPrograms {
-		if progID.Equals(r) {
-			return true
-		}
-	}
-	return false
-}
-
 type TokenTransfer struct {
 	mint     string
 	amount   uint64
@@ -62,13 +52,43 @@ type Parser struct {
 	txMeta          *rpc.TransactionMeta
 	txInfo          *solana.Transaction
 	allAccountKeys  solana.PublicKeySlice
+	accountIndexes  map[solana.PublicKey]int
 	splTokenInfoMap map[string]TokenInfo
 	splDecimalsMap  map[string]uint8
-	Log             *logrus.Logger
-
+	mintInfos       map[solana.PublicKey]MintInfo
+	mintExtensions  map[solana.PublicKey]MintExtensions
+	// pendingMints have no decimals in the transaction, see resolveMints.
+	pendingMints   []solana.PublicKey
+	token2022Mints []solana.PublicKey
+	uiScaledMints  map[solana.PublicKey]bool
+	transferFees    map[transferFeeKey]uint64
+	nonSwapLamports map[solana.PublicKey]bool
+	rent            RentLedger
+	tips            map[solana.PublicKey]uint64
+	slot            uint64
+	blockTime       time.Time
+	txIndex         int
+	logger          Logger
+	log             Logger
+	config          *ParserConfig
+	diagnostics     *Diagnostics
+	currentDiag     *InstructionDiagnostic
+
+	preBalance  map[uint16]*rpc.TokenBalance
 	postBalance map[uint16]*rpc.TokenBalance
+
+	// deltas are the BalanceDeltas, computed on first use, deltaIndex
+	// finds them by owner and mint.
+	deltas     []BalanceDelta
+	deltaIndex map[ownerMint]int
+
+	// deferValues leaves the USD values of the legs to ParseTransactions,
+	// which shows them to a learning oracle in block order.
+	deferValues bool
 }
 
+// The discriminators below only seed NewParserConfig, see
+// WithSwapInstructions.
 var (
 		swapDiscriminator = map[string]bool{
 		calculateDiscriminator("global:swap"):                   true,
@@ -108,34 +128,73 @@ var (
 	}
 )
 
-func NewTransactionParser(tx *solana.Transaction, txMeta *rpc.TransactionMeta) (*Parser, error) {
-	return NewTransactionParserFromTransaction(tx, txMeta)
+func NewTransactionParser(tx *solana.Transaction, txMeta *rpc.TransactionMeta, opts ...Option) (*Parser, error) {
+	return NewTransactionParserFromTransaction(tx, txMeta, opts...)
+}
+
+// NewParser returns a parser for a getTransaction result. Each call builds a
+// ParserConfig from opts, use NewParserWithConfig to share one.
+func NewParser(tx *rpc.GetTransactionResult, opts ...Option) (*Parser, error) {
+	return NewParserWithConfig(tx, NewParserConfig(opts...))
 }
 
-func NewParser(tx *rpc.GetTransactionResult) (*Parser, error) {
+func NewParserWithConfig(tx *rpc.GetTransactionResult, config *ParserConfig) (*Parser, error) {
+	if tx == nil || tx.Transaction == nil {
+		return nil, ErrMissingTransaction
+	}
 	txInfo, err := tx.Transaction.GetTransaction()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
 
-	return NewTransactionParserFromTransaction(txInfo, tx.Meta)
+	parser, err := NewTransactionParserWithConfig(txInfo, tx.Meta, config)
+	if err != nil {
+		return nil, err
+	}
+	var blockTime time.Time
+	if tx.BlockTime != nil {
+		blockTime = tx.BlockTime.Time()
+	}
+	parser.SetBlockContext(tx.Slot, blockTime, -1)
+	return parser, nil
+}
+
+func NewTransactionParserFromTransaction(tx *solana.Transaction, txMeta *rpc.TransactionMeta, opts ...Option) (*Parser, error) {
+	return NewTransactionParserWithConfig(tx, txMeta, NewParserConfig(opts...))
 }
 
-func NewTransactionParserFromTransaction(tx *solana.Transaction, txMeta *rpc.TransactionMeta) (*Parser, error) {
+func NewTransactionParserWithConfig(tx *solana.Transaction, txMeta *rpc.TransactionMeta, config *ParserConfig) (*Parser, error) {
+	if config == nil {
+		config = NewParserConfig()
+	}
+	if tx == nil {
+		return nil, ErrMissingTransaction
+	}
+	if txMeta == nil {
+		return nil, ErrMissingMeta
+	}
 	allAccountKeys := append(tx.Message.AccountKeys, txMeta.LoadedAddresses.Writable...)
 	allAccountKeys = append(allAccountKeys, txMeta.LoadedAddresses.ReadOnly...)
 
-	log := logrus.New()
-	log.SetFormatter(&logrus.TextFormatter{
-		TimestampFormat: "2006-01-02 15:04:05",
-		FullTimestamp:   true,
-	})
-
 	parser := &Parser{
 		txMeta:         txMeta,
 		txInfo:         tx,
 		allAccountKeys: allAccountKeys,
-		Log:            log,
+		config:         config,
+		logger:         config.logger,
+		txIndex:        -1,
+	}
+	if len(tx.Signatures) > 0 {
+		parser.logger = parser.logger.With("signature", tx.Signatures[0])
+	}
+	parser.log = parser.logger
+
+	if err := parser.validateAccountIndexes(); err != nil {
+		return nil, err
+	}
+	parser.accountIndexes = make(map[solana.PublicKey]int, len(allAccountKeys))
+	for i := len(allAccountKeys) - 1; i >= 0; i-- {
+		parser.accountIndexes[allAccountKeys[i]] = i
 	}
 
 	if err := parser.extractSPLTokenInfo(); err != nil {
@@ -145,9 +204,11 @@ func NewTransactionParserFromTransaction(tx *solana.Transaction, txMeta *rpc.Tra
 	if err := parser.extractSPLDecimals(); err != nil {
 		return nil, fmt.Errorf("failed to extract SPL decimals: %w", err)
 	}
+	parser.extractToken2022Info()
+	parser.extractNativeFlows()
 
-	if err := parser.extractAccountPostBalance(); err != nil {
-		return nil, fmt.Errorf("failed to extract SPL decimals: %w", err)
+	if err := parser.extractAccountBalances(); err != nil {
+		return nil, fmt.Errorf("failed to extract account balances: %w", err)
 	}
 
 	return parser, nil
@@ -157,86 +218,176 @@ type SwapData struct {
 	Type SwapType
 	Data interface{}
 	Tx   *TxInfo
+
+	// outer is the index of the outer instruction the leg was parsed from.
+	outer int
+	// inner is the inner instruction an event leg without a TxInfo, such as
+	// a Jupiter route event, was read from.
+	inner int
 }
 
+// ParseTransaction returns the swap legs of the transaction. Instructions
+// that fail to parse are skipped unless the parser is strict, use
+// ParseTransactionWithDiagnostics to find out why.
 func (p *Parser) ParseTransaction() ([]SwapData, error) {
+	swaps, _, err := p.ParseTransactionWithDiagnostics()
+	return swaps, err
+}
+
+// ParseTransactionWithDiagnostics is ParseTransaction that also reports
+// which handler claimed every examined instruction and why the others were
+// skipped.
+func (p *Parser) ParseTransactionWithDiagnostics() ([]SwapData, *Diagnostics, error) {
+	p.diagnostics = &Diagnostics{}
+	if len(p.txInfo.Signatures) > 0 {
+		p.diagnostics.Signature = p.txInfo.Signatures[0]
+	}
+
+	p.diagnostics.Status = p.Status()
+
+	var parsedSwaps []SwapData
+	switch {
+	case p.Status() == TxStatusFailed && !p.config.intents:
+		p.diagnostics.finish()
+		return nil, p.diagnostics, nil
+	case p.Status() == TxStatusFailed:
+		parsedSwaps = p.intendedSwaps()
+	default:
+		parsedSwaps = p.parseInstructions()
+		if len(parsedSwaps) == 0 && p.config.inference {
+			parsedSwaps = p.inferSwap()
+		}
+	}
+	p.resolveMints(parsedSwaps)
+	for _, swap := range parsedSwaps {
+		if swap.Tx == nil {
+			continue
+		}
+		if swap.Tx.Method == "" {
+			swap.Tx.Method = MethodParsed
+		}
+		swap.Tx.Status = p.Status()
+		p.setReserves(swap.Tx)
+	}
+	p.identify(parsedSwaps)
+	p.setToken2022Info(parsedSwaps)
+	for _, swap := range parsedSwaps {
+		if swap.Tx != nil {
+			swap.Tx.setPrices()
+			p.setSide(swap.Tx)
+		}
+	}
+	p.valueLegs(parsedSwaps)
+	if p.Status() == TxStatusSuccess {
+		p.validateLegs(parsedSwaps)
+	}
+	p.diagnostics.finish()
+
+	if p.config.strict {
+		if err := p.diagnostics.Err(); err != nil {
+			return nil, p.diagnostics, err
+		}
+		var discrepancies []Discrepancy
+		for _, swap := range parsedSwaps {
+			if swap.Tx != nil {
+				discrepancies = append(discrepancies, swap.Tx.Discrepancies...)
+			}
+		}
+		if err := inconsistency(discrepancies); err != nil {
+			return nil, p.diagnostics, err
+		}
+	}
+	return parsedSwaps, p.diagnostics, nil
+}
+
+// parseInstructions dispatches the outer instructions to the protocol
+// handlers.
+func (p *Parser) parseInstructions() []SwapData {
 	var parsedSwaps []SwapData
 
+	outerDiags := make([]*InstructionDiagnostic, len(p.txInfo.Message.Instructions))
 	skip := false
 	for i, outerInstruction := range p.txInfo.Message.Instructions {
 		progID := p.allAccountKeys[outerInstruction.ProgramIDIndex]
-		switch {
-		case progID.Equals(JUPITER_PROGRAM_ID) || progID.Equals(DFLOW_AGGREGATOR_V4):
-			skip = true
-			jupiterSwaps := p.processJupiterSwaps(i)
-			if len(jupiterSwaps) > 0 {
-				parsedSwaps = append(parsedSwaps, jupiterSwaps...)
-			} else {
-				// Fallback: RouteV2 or other newer Jupiter instructions may not emit
-				// JupiterRouteEvent. In that case scan inner instructions like a
-				// normal router.
-				parsedSwaps = append(parsedSwaps, p.processRouterSwaps(i)...)
-			}
-		case progID.Equals(MOONSHOT_PROGRAM_ID):
-			skip = true
-			parsedSwaps = append(parsedSwaps, p.processMoonshotSwaps()...)
-		case isRouterProgram(progID):
-			if innerSwaps := p.processRouterSwaps(i); len(innerSwaps) > 0 {
-				parsedSwaps = append(parsedSwaps, innerSwaps...)
-			}
-		case progID.Equals(OKX_DEX_ROUTER_PROGRAM_ID):
-			skip = true
-			parsedSwaps = append(parsedSwaps, p.processOKXSwaps(i)...)
-		case progID.Equals(RAYDIUM_AMM_ROUTER_PROGRAM_ID):
+		outerDiags[i] = p.diagnostics.add(i, -1, progID)
+		handler := p.matchProtocol(progID, outerInstruction, RoleAggregator, RoleRouter)
+		if handler == nil {
+			continue
+		}
+		if handler.Role() == RoleAggregator {
 			skip = true
-			parsedSwaps = append(parsedSwaps, p.processRaydSwaps(RAYDIUM_AMM_ROUTER_PROGRAM_ID, i, 0, &outerInstruction, true)...)
 		}
+		parsedSwaps = append(parsedSwaps, p.dispatch(handler, p.newProtocolContext(progID, outerInstruction, i, -1, nil), outerDiags[i])...)
 	}
 	if skip {
-		return parsedSwaps, nil
+		for _, diag := range outerDiags {
+			if diag.Handler == "" {
+				diag.SkipReason = SkipReasonAggregator
+			}
+		}
+		return parsedSwaps
 	}
 
 	for i, outerInstruction := range p.txInfo.Message.Instructions {
 		progID := p.allAccountKeys[outerInstruction.ProgramIDIndex]
-		switch {
-		case progID.Equals(RAYDIUM_V4_PROGRAM_ID) ||
-			progID.Equals(RAYDIUM_CPMM_PROGRAM_ID) ||
-			// progID.Equals(RAYDIUM_AMM_ROUTER_PROGRAM_ID) ||
-			progID.Equals(RAYDIUM_CONCENTRATED_LIQUIDITY_PROGRAM_ID) ||
-			progID.Equals(RAYDIUM_LAUNCHLAB_PROGRAM_ID):
-			parsedSwaps = append(parsedSwaps, p.processRaydSwaps(progID, i, 0, &outerInstruction, false)...)
-		case progID.Equals(ORCA_PROGRAM_ID):
-			parsedSwaps = append(parsedSwaps, p.processOrcaSwaps(i)...)
-		case progID.Equals(METEORA_PROGRAM_ID) || progID.Equals(METEORA_POOLS_PROGRAM_ID) || progID.Equals(METEORA_DLMM_PROGRAM_ID) ||
-			progID.Equals(Meteora_Dynamic_Bonding_Curve_Program) ||
-			progID.Equals(METEORA_DAMM_V2):
-			meteoraSwaps := p.processMeteoraSwaps(progID, i, 0, false)
-			if len(meteoraSwaps) > 0 {
-				parsedSwaps = append(parsedSwaps, meteoraSwaps...)
-			} else {
-				// Fallback: some Meteora programs act as routers (e.g. King7ki... DLMM router)
-				parsedSwaps = append(parsedSwaps, p.processRouterSwaps(i)...)
-			}
-		case progID.Equals(PUMPFUN_AMM_PROGRAM_ID):
-			parsedSwaps = append(parsedSwaps, p.processPumpfunAMMSwaps(i, false)...)
-		case progID.Equals(ZEROFI):
-			parsedSwaps = append(parsedSwaps, p.processZerofiSwaps(i, false)...)
-		case progID.Equals(HUMIDIDI_PROGRAM_ID):
-			parsedSwaps = append(parsedSwaps, p.processHumidifiSwaps(i, 0, &outerInstruction)...)
-		case progID.Equals(PUMP_FUN_PROGRAM_ID) ||
-			progID.Equals(solana.MustPublicKeyFromBase58("BSfD6SHZigAfDWSjzD5Q41jw8LmKwtmjskPH9XW1mrRW")):
-			parsedSwaps = append(parsedSwaps, p.processPumpfunSwaps(i)...)
+		if handler := p.matchProtocol(progID, outerInstruction, RoleAMM); handler != nil {
+			parsedSwaps = append(parsedSwaps, p.dispatch(handler, p.newProtocolContext(progID, outerInstruction, i, -1, nil), outerDiags[i])...)
+		}
+	}
+
+	return parsedSwaps
+}
+
+// dispatch parses the instruction of ctx with handler, recording the outcome
+// in diag.
+func (p *Parser) dispatch(handler ProtocolHandler, ctx *ProtocolContext, diag *InstructionDiagnostic) []SwapData {
+	parent, parentLog := p.currentDiag, p.log
+	p.currentDiag = diag
+	p.log = p.logger.With("program", ctx.ProgramID, "outer", ctx.OuterIndex, "inner", ctx.InnerIndex, "handler", handler.Name())
+	defer func() { p.currentDiag, p.log = parent, parentLog }()
+
+	ctx.diag = diag
+	diag.Handler = handler.Name()
+	swaps := handler.Parse(ctx)
+	for i := range swaps {
+		swaps[i].outer = ctx.OuterIndex
+		if tx := swaps[i].Tx; tx != nil && !tx.positioned {
+			tx.setPosition(ctx.OuterIndex, ctx.InnerIndex)
 		}
 	}
+	diag.Swaps += len(swaps)
+	return swaps
+}
 
-	return parsedSwaps, nil
+func (p *Parser) newProtocolContext(progID solana.PublicKey, ix solana.CompiledInstruction, outerIndex, innerIndex int, processed map[string]bool) *ProtocolContext {
+	if processed == nil {
+		processed = make(map[string]bool)
+	}
+	return &ProtocolContext{
+		ProgramID:   progID,
+		Instruction: ix,
+		OuterIndex:  outerIndex,
+		InnerIndex:  innerIndex,
+		IsInner:     innerIndex >= 0,
+		parser:      p,
+		processed:   processed,
+	}
 }
 
 type SwapInfo struct {
 	Signers    []solana.PublicKey
 	Signatures []solana.Signature
 	AMMs       []string
-	Timestamp  time.Time
+	// Timestamp is the BlockTime, or the ProtocolTimestamp when the node
+	// did not report one.
+	Timestamp time.Time
+
+	Slot uint64
+	// BlockTime is zero when the node did not report it.
+	BlockTime time.Time
+	// ProtocolTimestamp is the clock the AMM logged in its swap event, zero
+	// for AMMs that log none.
+	ProtocolTimestamp time.Time
 
 	TokenInMint     solana.PublicKey
 	TokenInAmount   uint64
@@ -245,9 +396,72 @@ type SwapInfo struct {
 	TokenOutMint     solana.PublicKey
 	TokenOutAmount   uint64
 	TokenOutDecimals uint8
+	// Side is TxSwapSideBuy when the signer spent the quote asset and
+	// TxSwapSideSell when they received it, see WithQuoteAssets.
+	Side        string
+	BaseMint    solana.PublicKey
+	QuoteMint   solana.PublicKey
+	BaseAmount  uint64
+	QuoteAmount uint64
+	// Price is the output received per input paid over the whole swap, in
+	// whole tokens.
+	Price *decimal.Decimal
+	// AmountInUSD and AmountOutUSD are the amounts valued by the oracle of
+	// WithPriceOracle, nil without one or when it has no price.
+	AmountInUSD  *decimal.Decimal
+	AmountOutUSD *decimal.Decimal
+
+	// Rent is what the signer paid for and got back from accounts opened
+	// and closed along the swap, it is not part of the amounts above.
+	Rent RentLedger
+	// Costs is what the whole transaction cost besides the swap, Rent
+	// included. Rent and Costs belong to the transaction, so they are only
+	// set on the first swap of its fee payer or bot fee authority and are
+	// zero and nil on the others, summing them over swaps counts them once.
+	Costs *Costs
+
+	// Status is TxStatusFailed for the intended swap of a failed
+	// transaction, see WithFailedSwapIntents.
+	Status TxStatus
+
+	// Legs are the swap data the amounts above were built from.
+	Legs []SwapData
+	// Route links the hops of the legs, nil for swaps parsed from a single
+	// event such as Pump.fun and Moonshot.
+	Route *Route
+	// Arbitrage is set when the route is a cycle, the amounts above are
+	// then what went in and came back out of it.
+	Arbitrage *ArbitrageInfo
+
+	// Confidence is the share of the checks against the balance changes of
+	// the signer that agreed with the amounts, 0 when none could be made.
+	Confidence    float64
+	Discrepancies []Discrepancy
 }
 
+// ProcessSwapData summarizes the legs into the swap of the signer. A strict
+// parser rejects a summary that disagrees with the balance changes.
 func (p *Parser) ProcessSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, error) {
+	swapInfo, tx, err := p.processSwapData(swapDatas)
+	if err != nil || swapInfo == nil {
+		return swapInfo, tx, err
+	}
+	p.enrich(swapInfo)
+	p.attachCosts(swapInfo)
+	swapInfo.Status = p.Status()
+	if swapInfo.Status == TxStatusFailed {
+		return swapInfo, tx, nil
+	}
+	p.validateSwapInfo(swapInfo)
+	if p.config.strict {
+		if err := inconsistency(swapInfo.Discrepancies); err != nil {
+			return nil, tx, err
+		}
+	}
+	return swapInfo, tx, nil
+}
+
+func (p *Parser) processSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, error) {
 	if len(swapDatas) == 0 {
 		return nil, nil, fmt.Errorf("no swap data provided")
 	}
@@ -258,14 +472,18 @@ func (p *Parser) ProcessSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, erro
 
 	swapInfo := &SwapInfo{
 		Signatures: p.txInfo.Signatures,
+		Legs:       swapDatas,
+		Slot:       p.slot,
+		BlockTime:  p.blockTime,
 	}
-
-	if p.containsDCAProgram() {
-		swapInfo.Signers = []solana.PublicKey{p.allAccountKeys[2]}
-	} else {
-		swapInfo.Signers = []solana.PublicKey{p.allAccountKeys[0]}
+	swapInfo.ProtocolTimestamp = protocolTimestamp(swapDatas)
+	swapInfo.Timestamp = swapInfo.BlockTime
+	if swapInfo.Timestamp.IsZero() {
+		swapInfo.Timestamp = swapInfo.ProtocolTimestamp
 	}
 
+	swapInfo.Signers = []solana.PublicKey{p.signer()}
+
 	jupiterSwaps := make([]SwapData, 0)
 	pumpfunSwaps := make([]SwapData, 0)
 	moonshotSwaps := make([]SwapData, 0)
@@ -285,19 +503,12 @@ func (p *Parser) ProcessSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, erro
 	}
 
 	if len(jupiterSwaps) > 0 {
-		jupiterInfo, err := parseJupiterEvents(jupiterSwaps)
+		route, err := p.buildRoute(jupiterSwaps)
 		if err != nil {
 			return nil, tx, fmt.Errorf("failed to parse Jupiter events: %w", err)
 		}
-
-		swapInfo.TokenInMint = jupiterInfo.TokenInMint
-		swapInfo.TokenInAmount = jupiterInfo.TokenInAmount
-		swapInfo.TokenInDecimals = jupiterInfo.TokenInDecimals
-		swapInfo.TokenOutMint = jupiterInfo.TokenOutMint
-		swapInfo.TokenOutAmount = jupiterInfo.TokenOutAmount
-		swapInfo.TokenOutDecimals = jupiterInfo.TokenOutDecimals
-		swapInfo.AMMs = jupiterInfo.AMMs
-
+		swapInfo.setRoute(route)
+		swapInfo.Arbitrage = p.arbitrage(route, swapInfo.Signers[0])
 		return swapInfo, tx, nil
 	}
 
@@ -310,17 +521,16 @@ func (p *Parser) ProcessSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, erro
 				swapInfo.TokenInDecimals = 9
 				swapInfo.TokenOutMint = data.Mint
 				swapInfo.TokenOutAmount = data.TokenAmount
-				swapInfo.TokenOutDecimals = p.splDecimalsMap[data.Mint.String()]
+				swapInfo.TokenOutDecimals = p.knownDecimals(data.Mint)
 			} else {
 				swapInfo.TokenInMint = data.Mint
 				swapInfo.TokenInAmount = data.TokenAmount
-				swapInfo.TokenInDecimals = p.splDecimalsMap[data.Mint.String()]
+				swapInfo.TokenInDecimals = p.knownDecimals(data.Mint)
 				swapInfo.TokenOutMint = NATIVE_SOL_MINT_PROGRAM_ID
 				swapInfo.TokenOutAmount = data.SolAmount
 				swapInfo.TokenOutDecimals = 9
 			}
 			swapInfo.AMMs = append(swapInfo.AMMs, string(pumpfunSwaps[0].Type))
-			swapInfo.Timestamp = time.Unix(int64(data.Timestamp), 0)
 			return swapInfo, tx, nil
 		default:
 			otherSwaps = append(otherSwaps, pumpfunSwaps...)
@@ -335,17 +545,16 @@ func (p *Parser) ProcessSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, erro
 				swapInfo.TokenInDecimals = 9
 				swapInfo.TokenOutMint = data.Mint
 				swapInfo.TokenOutAmount = data.TokenAmount
-				swapInfo.TokenOutDecimals = p.splDecimalsMap[data.Mint.String()]
+				swapInfo.TokenOutDecimals = p.knownDecimals(data.Mint)
 			} else {
 				swapInfo.TokenInMint = data.Mint
 				swapInfo.TokenInAmount = data.TokenAmount
-				swapInfo.TokenInDecimals = p.splDecimalsMap[data.Mint.String()]
+				swapInfo.TokenInDecimals = p.knownDecimals(data.Mint)
 				swapInfo.TokenOutMint = NATIVE_SOL_MINT_PROGRAM_ID
 				swapInfo.TokenOutAmount = data.CollateralAmount
 				swapInfo.TokenOutDecimals = 9
 			}
 			swapInfo.AMMs = append(swapInfo.AMMs, string(moonshotSwaps[0].Type))
-			swapInfo.Timestamp = time.Now()
 			return swapInfo, tx, nil
 		}
 	}
@@ -361,55 +570,19 @@ func (p *Parser) ProcessSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, erro
 			}
 		}
 		if firstTxBasedIdx != -1 {
-			// Collect all tx-based swaps in ORIGINAL order (from swapDatas)
+			// buildRoute orders the hops by execution index
 			var txSwaps []SwapData
 			for _, sd := range swapDatas {
 				if sd.Tx != nil && sd.Data == nil {
 					txSwaps = append(txSwaps, sd)
 				}
 			}
-			if len(txSwaps) > 0 {
-				// Build input/output mint sets
-				inputMints := make(map[string]bool)
-				outputMints := make(map[string]bool)
-				for _, sd := range txSwaps {
-					inputMints[sd.Tx.InputMint.String()] = true
-					outputMints[sd.Tx.OutputMint.String()] = true
-				}
-				var startSwap, endSwap *SwapData
-				for i := range txSwaps {
-					sd := &txSwaps[i]
-					// Start: input mint is not anyone's output
-					if !outputMints[sd.Tx.InputMint.String()] {
-						startSwap = sd
-					}
-					// End: output mint is not anyone's input
-					if !inputMints[sd.Tx.OutputMint.String()] {
-						endSwap = sd
-					}
-				}
-				if startSwap == nil {
-					startSwap = &txSwaps[0]
-				}
-				if endSwap == nil {
-					endSwap = &txSwaps[len(txSwaps)-1]
-				}
-				swapInfo.TokenInMint = startSwap.Tx.InputMint
-				swapInfo.TokenInAmount = startSwap.Tx.InputAmount
-				swapInfo.TokenInDecimals = startSwap.Tx.InputMintDecimals
-				swapInfo.TokenOutMint = endSwap.Tx.OutputMint
-				swapInfo.TokenOutAmount = endSwap.Tx.OutputAmount
-				swapInfo.TokenOutDecimals = endSwap.Tx.OutputMintDecimals
-			}
-
-			seenAMMs := make(map[string]bool)
-			for _, sd := range otherSwaps {
-				if !seenAMMs[string(sd.Type)] {
-					swapInfo.AMMs = append(swapInfo.AMMs, string(sd.Type))
-					seenAMMs[string(sd.Type)] = true
-				}
+			route, err := p.buildRoute(txSwaps)
+			if err != nil {
+				return nil, tx, err
 			}
-			swapInfo.Timestamp = time.Now()
+			swapInfo.setRoute(route)
+			swapInfo.Arbitrage = p.arbitrage(route, swapInfo.Signers[0])
 			return swapInfo, tx, nil
 		}
 
@@ -450,10 +623,10 @@ func (p *Parser) ProcessSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, erro
 				}
 			}
 
-			swapInfo.TokenInMint = solana.MustPublicKeyFromBase58(inputTransfer.mint)
+			swapInfo.TokenInMint = mintFromString(inputTransfer.mint)
 			swapInfo.TokenInAmount = totalInputAmount
 			swapInfo.TokenInDecimals = inputTransfer.decimals
-			swapInfo.TokenOutMint = solana.MustPublicKeyFromBase58(outputTransfer.mint)
+			swapInfo.TokenOutMint = mintFromString(outputTransfer.mint)
 			swapInfo.TokenOutAmount = totalOutputAmount
 			swapInfo.TokenOutDecimals = outputTransfer.decimals
 
@@ -465,13 +638,11 @@ func (p *Parser) ProcessSwapData(swapDatas []SwapData) (*SwapInfo, *TxInfo, erro
 				}
 			}
 
-			swapInfo.Timestamp = time.Now()
-
 			return swapInfo, tx, nil
 		}
 	}
 
-	return nil, nil, fmt.Errorf("no valid swaps found")
+	return nil, nil, ErrNoValidSwaps
 }
 
 func getTransferFromSwapData(swapData SwapData) *TokenTransfer {
@@ -508,52 +679,9 @@ func (p *Parser) processRouterSwaps(instructionIndex int) []SwapData {
 
 	for idx, inner := range innerInstructions {
 		progID := p.allAccountKeys[inner.ProgramIDIndex]
-
-		switch {
-		case (progID.Equals(RAYDIUM_V4_PROGRAM_ID) ||
-			progID.Equals(RAYDIUM_CPMM_PROGRAM_ID) ||
-			// progID.Equals(RAYDIUM_AMM_ROUTER_PROGRAM_ID) ||
-			progID.Equals(RAYDIUM_CONCENTRATED_LIQUIDITY_PROGRAM_ID)) && !processedProtocols[PROTOCOL_RAYDIUM]:
-			processedProtocols[PROTOCOL_RAYDIUM] = true
-			if raydSwaps := p.processRaydSwaps(progID, instructionIndex, idx, &inner, true); len(raydSwaps) > 0 {
-				swaps = append(swaps, raydSwaps...)
-			}
-
-		case progID.Equals(ORCA_PROGRAM_ID) && !processedProtocols[PROTOCOL_ORCA]:
-			processedProtocols[PROTOCOL_ORCA] = true
-			if orcaSwaps := p.processOrcaSwaps(instructionIndex); len(orcaSwaps) > 0 {
-				swaps = append(swaps, orcaSwaps...)
-			}
-
-		case (progID.Equals(METEORA_PROGRAM_ID) ||
-			progID.Equals(METEORA_POOLS_PROGRAM_ID) ||
-			progID.Equals(METEORA_DLMM_PROGRAM_ID) ||
-			progID.Equals(METEORA_DAMM_V2)) && !processedProtocols[PROTOCOL_METEORA]:
-			processedProtocols[PROTOCOL_METEORA] = true
-			if meteoraSwaps := p.processMeteoraSwaps(progID, instructionIndex, idx, true); len(meteoraSwaps) > 0 {
-				swaps = append(swaps, meteoraSwaps...)
-			}
-
-		case progID.Equals(PUMPFUN_AMM_PROGRAM_ID) && !processedProtocols[PROTOCOL_PUMPFUN]:
-			processedProtocols[PROTOCOL_PUMPFUN] = true
-			if pumpfunAMMSwaps := p.processPumpfunAMMSwaps(instructionIndex, true); len(pumpfunAMMSwaps) > 0 {
-				swaps = append(swaps, pumpfunAMMSwaps...)
-			}
-
-		case (progID.Equals(PUMP_FUN_PROGRAM_ID) ||
-			progID.Equals(solana.MustPublicKeyFromBase58("BSfD6SHZigAfDWSjzD5Q41jw8LmKwtmjskPH9XW1mrRW"))) && !processedProtocols[PROTOCOL_PUMPFUN]:
-			processedProtocols[PROTOCOL_PUMPFUN] = true
-			if pumpfunSwaps := p.processPumpfunSwaps(instructionIndex); len(pumpfunSwaps) > 0 {
-				swaps = append(swaps, pumpfunSwaps...)
-			}
-		case progID.Equals(ZEROFI):
-			if zerofiSwaps := p.processZerofiSwaps(instructionIndex, true); len(zerofiSwaps) > 0 {
-				swaps = append(swaps, zerofiSwaps...)
-			}
-		case progID.Equals(HUMIDIDI_PROGRAM_ID):
-			if humidifiSwaps := p.processHumidifiSwaps(instructionIndex, idx, &inner); len(humidifiSwaps) > 0 {
-				swaps = append(swaps, humidifiSwaps...)
-			}
+		diag := p.diagnostics.add(instructionIndex, idx, progID)
+		if handler := p.matchProtocol(progID, inner, RoleAMM); handler != nil {
+			swaps = append(swaps, p.dispatch(handler, p.newProtocolContext(progID, inner, instructionIndex, idx, processedProtocols), diag)...)
 		}
 	}
 
@@ -590,456 +718,202 @@ type TxInfo struct {
 	Pool               solana.PublicKey
 	PoolIn             solana.PublicKey
 	PoolOut            solana.PublicKey
+	// PoolInAmount and PoolOutAmount are the reserves of the vaults after
+	// the transaction, PoolInPreAmount and PoolOutPreAmount before it.
 	PoolInAmount       *big.Int
 	PoolOutAmount      *big.Int
+	PoolInPreAmount    *big.Int
+	PoolOutPreAmount   *big.Int
 	Owner              solana.PublicKey
 	Router             solana.PublicKey
-	Index              uint
-	Protocol           string
+	// Index is OuterIndex * 256 + InnerIndex, kept for compatibility. It
+	// is the same for an outer instruction and its first inner one, order
+	// legs by OuterIndex and InnerIndex instead.
+	Index    uint
+	Protocol string
+	// Method tells how the leg was found, MethodParsed, MethodInferred or
+	// MethodIntended. An intended leg has the amount the trader asked the
+	// AMM for, InputAmount or OutputAmount, and its limit in
+	// MinOutputAmount or MaxInputAmount; the other amount is 0 and it has
+	// no ExecutionPrice.
+	Method          string
+	Status          TxStatus
+	MinOutputAmount uint64
+	MaxInputAmount  uint64
+	// Side, BaseMint and QuoteMint read the swap from the market of its
+	// mints, see WithQuoteAssets. They are empty for liquidity legs.
+	Side        string
+	BaseMint    solana.PublicKey
+	QuoteMint   solana.PublicKey
+	BaseAmount  uint64
+	QuoteAmount uint64
+
+	// Signature, Slot and TxIndexInBlock place the transaction of the leg,
+	// TxIndexInBlock is -1 unless the parser was given a block context.
+	Signature      solana.Signature
+	Slot           uint64
+	TxIndexInBlock int
+	// OuterIndex and InnerIndex are the instruction that executed the
+	// leg, InnerIndex is -1 for an outer instruction. StackHeight is its
+	// invocation depth, 1 for an outer instruction and 0 when the node did
+	// not report it.
+	OuterIndex  int
+	InnerIndex  int
+	StackHeight int
+	// EventID identifies the leg across parses, see EventID.
+	EventID string
+	// BlockTime is zero when the node did not report it.
+	BlockTime time.Time
+	// ProtocolTimestamp is the clock the AMM logged in its swap event:
+	// Pump.fun AMM Timestamp or Meteora DAMM v2 CurrentTimestamp. It is zero
+	// for AMMs that log none.
+	ProtocolTimestamp time.Time
+
+	// ExecutionPrice is the output received per input paid, in whole
+	// tokens. SpotPriceBefore and SpotPriceAfter are the ratio of the
+	// output to the input reserves of the pool before and after the
+	// transaction, the marginal price of constant product pools; pools
+	// with concentrated liquidity price off their active range and their
+	// ratio is only indicative. PriceImpactBps is how far the spot price
+	// moved against the trader in basis points. They are nil when the
+	// amounts or reserves are unknown.
+	ExecutionPrice  *decimal.Decimal
+	SpotPriceBefore *decimal.Decimal
+	SpotPriceAfter  *decimal.Decimal
+	PriceImpactBps  *decimal.Decimal
+	// AmountInUSD and AmountOutUSD are the amounts valued by the oracle of
+	// WithPriceOracle at the BlockTime, or the ProtocolTimestamp when it is
+	// unknown. They are nil without an oracle or when it has no price.
+	AmountInUSD  *decimal.Decimal
+	AmountOutUSD *decimal.Decimal
+
+	// Fees withheld by Token-2022 transfer fee mints. The amounts above
+	// are the gross amounts sent.
+	InputTransferFee     uint64
+	OutputTransferFee    uint64
+	InputMintExtensions  MintExtensions
+	OutputMintExtensions MintExtensions
+
+	// Confidence is the share of the checks against the balance changes
+	// that agreed with the leg, 0 when none could be made.
+	Confidence    float64
+	Discrepancies []Discrepancy
+
+	positioned bool
+	// lamportVaults maps the lamport vaults of the pool to their data size.
+	lamportVaults map[solana.PublicKey]uint64
+}
+
+// NetInputAmount returns the input amount received by the pool.
+func (tx *TxInfo) NetInputAmount() uint64 {
+	return tx.InputAmount - min(tx.InputTransferFee, tx.InputAmount)
+}
+
+// NetOutputAmount returns the output amount received by the trader.
+func (tx *TxInfo) NetOutputAmount() uint64 {
+	return tx.OutputAmount - min(tx.OutputTransferFee, tx.OutputAmount)
 }
 
 func (p *Parser) setTxPoolInfo(progID solana.PublicKey, tx *TxInfo, instruction solana.CompiledInstruction) (err error) {
-	var discriminatorLen = 8
-	var discriminatorWhiteList [][]byte
-	var poolAccountIndex, poolInAccountIndex, poolOutAccountIndex uint16
-	var protocol string
-	pid := progID.String()
-	tx.Type = TxTypeSwap
-	switch {
-	case progID.Equals(RAYDIUM_V4_PROGRAM_ID):
-		poolAccountIndex = 1
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		//fromAccountIndex = 16
-		//toAccountIndex = 2
-		if len(instruction.Accounts) == 18 {
-			poolInAccountIndex = 5
-			poolOutAccountIndex = 6
-			//fromAccountIndex = 17
-			//toAccountIndex = 2
-		}
-		protocol = string(RAYDIUM)
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},  // add
-			{3},  // add
-			{4},  // remove
-			{9},  // swap
-			{11}, // swap
-		}
-		if len(instruction.Data) > 0 {
-			if instruction.Data[0] == 1 {
-				tx.Type = TxTypeAdd
-				poolAccountIndex = 4
-				poolInAccountIndex = 10
-				poolOutAccountIndex = 11
-			} else if instruction.Data[0] == 3 {
-				tx.Type = TxTypeAdd
-				poolAccountIndex = 1
-				poolInAccountIndex = 6
-				poolOutAccountIndex = 7
-			} else if instruction.Data[0] == 4 {
-				tx.Type = TxTypeRemove
-				poolAccountIndex = 1
-				poolInAccountIndex = 6
-				poolOutAccountIndex = 7
-			}
-		}
-	case progID.Equals(ORCA_PROGRAM_ID):
-		poolAccountIndex = 2
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 6
-		//fromAccountIndex = 1
-		//toAccountIndex = 2
-		protocol = string(ORCA)
-		if len(instruction.Data) >= discriminatorLen {
-			if _, ok := removeDiscriminator[hex.EncodeToString(instruction.Data[:discriminatorLen])]; ok {
-				tx.Type = TxTypeRemove
-				poolAccountIndex = 0
-				poolInAccountIndex = uint16(len(instruction.Accounts)) - 4
-				poolOutAccountIndex = uint16(len(instruction.Accounts)) - 3
-			} else if _, ok := addDiscriminator[hex.EncodeToString(instruction.Data[:discriminatorLen])]; ok {
-				tx.Type = TxTypeAdd
-				poolAccountIndex = 0
-				poolInAccountIndex = uint16(len(instruction.Accounts)) - 4
-				poolOutAccountIndex = uint16(len(instruction.Accounts)) - 3
-			}
-		}
-	case progID.Equals(RAYDIUM_CPMM_PROGRAM_ID):
-		poolAccountIndex = 3
-		poolInAccountIndex = 6
-		poolOutAccountIndex = 7
-		//fromAccountIndex = 0
-		//toAccountIndex = 1
-		protocol = string(RAYDIUM)
-		if len(instruction.Data) >= discriminatorLen {
-			if _, ok := removeDiscriminator[hex.EncodeToString(instruction.Data[:discriminatorLen])]; ok {
-				tx.Type = TxTypeRemove
-				poolAccountIndex = 2
-				poolInAccountIndex = 6
-				poolOutAccountIndex = 7
-			} else if _, ok := addDiscriminator[hex.EncodeToString(instruction.Data[:discriminatorLen])]; ok {
-				tx.Type = TxTypeAdd
-				poolAccountIndex = 2
-				poolInAccountIndex = 6
-				poolOutAccountIndex = 7
-				if hex.EncodeToString(instruction.Data[:discriminatorLen]) == calculateDiscriminator("global:initialize") {
-					poolAccountIndex = 3
-					poolInAccountIndex = 10
-					poolOutAccountIndex = 11
-				}
-			}
-		}
-	case progID.Equals(RAYDIUM_CONCENTRATED_LIQUIDITY_PROGRAM_ID):
-		poolAccountIndex = 2
-		poolInAccountIndex = 5
-		poolOutAccountIndex = 6
-		//fromAccountIndex = 0
-		//toAccountIndex = 2
-		protocol = string(RAYDIUM)
-		if len(instruction.Data) >= discriminatorLen {
-			if _, ok := removeDiscriminator[hex.EncodeToString(instruction.Data[:discriminatorLen])]; ok {
-				tx.Type = TxTypeRemove
-				poolAccountIndex = 3
-				poolInAccountIndex = 5
-				poolOutAccountIndex = 6
-			} else if _, ok := addDiscriminator[hex.EncodeToString(instruction.Data[:discriminatorLen])]; ok {
-				tx.Type = TxTypeAdd
-				poolAccountIndex = 2
-				poolInAccountIndex = 9
-				poolOutAccountIndex = 10
-			}
-		}
-	case progID.Equals(RAYDIUM_LAUNCHLAB_PROGRAM_ID):
-		poolAccountIndex = 2
-		poolInAccountIndex = 8
-		poolOutAccountIndex = 7
-		protocol = "Raydium Launchlab"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},   // buy
-			{3},   // sell
-			{250}, // swap (0xfa)
-		}
-	case progID.Equals(METEORA_PROGRAM_ID):
-		poolAccountIndex = 0
-		poolInAccountIndex = 2
-		poolOutAccountIndex = 3
-		//fromAccountIndex = 0
-		//toAccountIndex = 10
-		protocol = "Meteora_DLMM_Program"
-		if len(instruction.Data) >= discriminatorLen {
-			liquidity := false
-			if _, ok := removeDiscriminator[hex.EncodeToString(instruction.Data[:discriminatorLen])]; ok {
-				tx.Type = TxTypeRemove
-				poolAccountIndex = 1
-				poolInAccountIndex = 5
-				poolOutAccountIndex = 6
-				liquidity = true
-			} else if _, ok := addDiscriminator[hex.EncodeToString(instruction.Data[:discriminatorLen])]; ok {
-				tx.Type = TxTypeAdd
-				poolAccountIndex = 1
-				poolInAccountIndex = 5
-				poolOutAccountIndex = 6
-				liquidity = true
-			}
-			if liquidity && tx.OutputAmount == 0 {
-				if tx.InputMint.Equals(p.allAccountKeys[instruction.Accounts[7]]) {
-					tx.OutputMint = p.allAccountKeys[instruction.Accounts[8]]
-					tx.OutputMintDecimals = p.splTokenInfoMap[p.allAccountKeys[instruction.Accounts[6]].String()].Decimals
-				} else {
-					tx.OutputMint = p.allAccountKeys[instruction.Accounts[7]]
-					tx.OutputMintDecimals = p.splTokenInfoMap[p.allAccountKeys[instruction.Accounts[5]].String()].Decimals
-				}
-			}
-		}
-	case pid == "swapFpHZwjELNnjvThjajtiVmkz3yPQEHjLtka2fwHW":
-		poolAccountIndex = 6
-		poolInAccountIndex = 3
-		poolOutAccountIndex = 4
-		if len(instruction.Accounts) == 15 {
-			poolAccountIndex = 8
-			poolInAccountIndex = 5
-			poolOutAccountIndex = 6
-		}
-		protocol = "StableWeighted"
-	case pid == "SoLFiHG9TfgtdUXUjWAxi3LtvYuFyDLVhBWxdMZxyCe":
-		poolAccountIndex = 1
-		poolInAccountIndex = 2
-		poolOutAccountIndex = 3
-		protocol = "SolFi"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{7},
+	// instructions that are not swaps, such as admin calls, are no parse
+	// errors
+	notSwap := false
+	defer func() {
+		if !notSwap {
+			p.noteError(err)
 		}
-	case pid == "2wT8Yq49kHgDzXuPxZSaeLaH1qbmGXtEyPy64bL7aD3c":
-		poolAccountIndex = 1
-		poolInAccountIndex = 5
-		poolOutAccountIndex = 6
-		protocol = "Lifinity Swap V2"
-	case pid == "9W959DqEETiGZocYWCQPaJ6sBmUzgfxXfqGeTEdp3aQP":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Orca Token Swap V2"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case progID.Equals(METEORA_POOLS_PROGRAM_ID):
-		poolAccountIndex = 0
-		poolInAccountIndex = 5
-		poolOutAccountIndex = 6
-		protocol = "Meteora Pools Program"
-	case progID.Equals(Meteora_Dynamic_Bonding_Curve_Program):
-		poolAccountIndex = 2
-		poolInAccountIndex = 5
-		poolOutAccountIndex = 6
-		protocol = "Meteora Dynamic Bonding Curve"
-	case pid == "swapNyd8XiQwJ6ianp9snpu4brUqFxadzvHebnAXjJZ":
-		poolAccountIndex = 6
-		poolInAccountIndex = 3
-		poolOutAccountIndex = 4
-		if len(instruction.Accounts) == 15 {
-			poolAccountIndex = 8
-			poolInAccountIndex = 5
-			poolOutAccountIndex = 6
-		}
-		protocol = "stabble Stable Swap"
-	case progID.Equals(PHOENIX_PROGRAM_ID):
-		poolAccountIndex = 2
-		poolInAccountIndex = 6
-		poolOutAccountIndex = 7
-		protocol = "Phoenix"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{0},
-		}
-	case pid == "DEXYosS6oEGvk8uCDayvwEZz4qEyDJRf9nFgYCaqPMTm":
-		poolAccountIndex = 2
-		poolInAccountIndex = 3
-		poolOutAccountIndex = 4
-		protocol = "1Dex"
-	case pid == "H8W3ctz92svYg6mkn1UtGfu2aQr2fnUFHM1RhScEtQDt":
-		poolAccountIndex = 2
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 6
-		protocol = "Cropper"
-	case pid == "HyaB3W9q6XdA5xwpU4XnSZV94htfmbmqJXZcEbRaJutt":
-		poolAccountIndex = 1
-		poolInAccountIndex = 5
-		poolOutAccountIndex = 6
-		protocol = "Invariant"
-	case pid == "SSwpkEEcbUqx4vtoEByFjSkhKdCT862DNVb52nZg1UZ":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Saber Stable Swap"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case pid == "SSwapUtytfBdBn1b9NUGG6foMVPtcWgpRU32HToDUZr":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Saros"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case pid == "FLUXubRmkEi2q6K3Y9kBPg9248ggaZVsoSFhtJHSrm1X":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Fluxbeam"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case pid == "Gswppe6ERWKpUTXvRPfXdzHhiCyJvLadVvXGfdpBqcE1":
-		poolAccountIndex = 1
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Guac"
-	case pid == "BSwp6bEBihVLdqJRKGgzjcGLHkcTuzmSo1TQkHepzH8p":
-		poolAccountIndex = 1
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "BonkSwap"
-	case pid == "DSwpgjMvXhtGn6BsbqmacdBZyfLj6jSWf3HJpdJtmg6N":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "DexlabSwap"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case pid == "CURVGoZn8zycx6FXwwevgBTB2gVvdbGTEpvMJDbgs2t4":
-		poolAccountIndex = 0
-		poolInAccountIndex = 3
-		poolOutAccountIndex = 4
-		protocol = "Aldrin"
-	case pid == "AMM55ShdkoGRB5jVYPjWziwk8m5MpwyDgsMWHaMSQWH6":
-		poolAccountIndex = 0
-		poolInAccountIndex = 3
-		poolOutAccountIndex = 4
-		protocol = "Aldrin"
-	case pid == "DjVE6JNiYqPL2QXyCUUh8rNjHrbz9hXHNYt99MQ59qw1":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Orca Token Swap"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case pid == "SwaPpA9LAaLfeLi3a68M4DjnLqgtticKg6CnyNwgAC8":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Swap Program"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case pid == "5jnapfrAN47UYkLkEf7HnprPPBCQLvkYWGZDeKkaP5hv":
-		poolAccountIndex = 4
-		poolInAccountIndex = 7
-		poolOutAccountIndex = 8
-		protocol = "DaoFun"
-	case pid == "CLMM9tUoggJu2wagPkkqs9eFG4BWhVBZWkP1qv3Sp7tR":
-		poolAccountIndex = 1
-		poolInAccountIndex = 6
-		poolOutAccountIndex = 7
-		protocol = "Crema Finance Program"
-	case pid == "Dooar9JkhdZ7J3LHN3A7YCuoGRUggXhQaG4kijfLGU2j":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "StepN DOOAR Swap"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case pid == "treaf4wWBBty3fHdyBpo35Mz84M8k3heKXmjmi9vFt5":
-		poolAccountIndex = 0
-		poolInAccountIndex = 3
-		poolOutAccountIndex = 4
-		protocol = "Helium Treasury Management"
-	case pid == "PSwapMdSai8tjrEXcxFeQth87xC4rRsa4VA5mhGhXkP":
-		poolAccountIndex = 0
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Penguin Finance"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{1},
-		}
-	case progID.Equals(PUMPFUN_AMM_PROGRAM_ID):
-		poolAccountIndex = 0
-		poolInAccountIndex = 7
-		poolOutAccountIndex = 8
-		protocol = string(PUMP_FUN)
-	case progID.Equals(METEORA_DAMM_V2):
-		poolAccountIndex = 1
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Meteora_DAMM_V2"
-	case progID.Equals(ZEROFI):
-		poolAccountIndex = 0
-		poolInAccountIndex = 2
-		poolOutAccountIndex = 4
-		protocol = "ZeroFi"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{6},
-		}
-	case progID.Equals(PANCAKE_SWAP_PROGRAM_ID):
-		poolAccountIndex = 2
-		poolInAccountIndex = 5
-		poolOutAccountIndex = 6
-		protocol = "PancakeSwap"
-	case progID.Equals(HUMIDIDI_PROGRAM_ID):
-		poolAccountIndex = 1
-		poolInAccountIndex = 2
-		poolOutAccountIndex = 3
-		protocol = "HumidiFi"
-		discriminatorWhiteList = [][]byte{
-			{149, 59, 131, 119, 245, 228, 249, 17},
-			{61, 203, 246, 110, 148, 191, 54, 98},
-			{94, 146, 200, 36, 3, 236, 122, 214},
-		}
-	case progID.Equals(TESSERA_V_PROGRAM_ID):
-		poolAccountIndex = 1
-		poolInAccountIndex = 4
-		poolOutAccountIndex = 5
-		protocol = "Tessera V"
-		discriminatorLen = 1
-		discriminatorWhiteList = [][]byte{
-			{16},
-		}
-	default:
-		err = errors.New("unknown progID")
-		log.Println("unknown progID", p.txInfo.Signatures, progID)
-		return
-	}
+	}()
 
-	if len(instruction.Data) < discriminatorLen {
-		err = errors.New("invalid instruction data length")
+	tx.Type = TxTypeSwap
+	descriptor, ok := p.config.ammRegistry.Lookup(progID)
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrUnknownProgram, progID)
+		p.log.Debug("no amm descriptor", "amm", progID)
 		return
 	}
 
-	discriminator := hex.EncodeToString(instruction.Data[:discriminatorLen])
-	var m map[string]bool
-	if len(discriminatorWhiteList) > 0 {
-		m = map[string]bool{}
-		for _, d := range discriminatorWhiteList {
-			m[hex.EncodeToString(d)] = true
+	layout, err := descriptor.match(instruction.Data, len(instruction.Accounts), p.config.kinds)
+	if err != nil {
+		p.log.Debug("amm instruction not matched", "amm", progID, "data", hex.EncodeToString(instruction.Data), "err", err)
+		notSwap = true
+		if p.currentDiag != nil && p.currentDiag.SkipReason == "" {
+			p.currentDiag.SkipReason, p.currentDiag.SkipErr = SkipReasonNotSwap, err
 		}
-	} else {
-		m = swapDiscriminator
-	}
-	if _, ok := m[discriminator]; ok {
-	} else if _, ok := removeDiscriminator[discriminator]; ok {
-	} else if _, ok := addDiscriminator[discriminator]; ok {
-	} else {
-		err = errors.New("discriminator unmatched")
-		log.Println(err, p.txInfo.Signatures, progID, hex.EncodeToString(instruction.Data), discriminator)
 		return
 	}
+	tx.Type = layout.Type
 
 	accLen := len(instruction.Accounts)
-	if accLen < int(poolAccountIndex) || accLen <= int(poolOutAccountIndex) || accLen < int(poolInAccountIndex) {
-		err = fmt.Errorf("account index out of range %d/%d-%d-%d", len(instruction.Accounts), poolAccountIndex, poolInAccountIndex, poolOutAccountIndex)
+	resolve := func(idx int) (uint16, bool) {
+		if idx < 0 {
+			idx += accLen
+		}
+		if idx < 0 || idx >= accLen {
+			return 0, false
+		}
+		return uint16(idx), true
+	}
+	poolAccountIndex, okPool := resolve(layout.Accounts.Pool)
+	poolInAccountIndex, okIn := resolve(layout.Accounts.PoolIn)
+	poolOutAccountIndex, okOut := resolve(layout.Accounts.PoolOut)
+	if !okPool || !okIn || !okOut {
+		err = fmt.Errorf("%w: %d/%d-%d-%d", ErrAccountIndexOutOfRange, accLen, layout.Accounts.Pool, layout.Accounts.PoolIn, layout.Accounts.PoolOut)
 		return
 	}
 
-	poolInAccountIndex = instruction.Accounts[poolInAccountIndex]
-	poolOutAccountIndex = instruction.Accounts[poolOutAccountIndex]
+	// liquidity instructions may move a single side, take the other mint of the pair
+	if len(layout.MintAccounts) == 2 && tx.OutputAmount == 0 {
+		mintX, okX := resolve(layout.MintAccounts[0])
+		mintY, okY := resolve(layout.MintAccounts[1])
+		if okX && okY {
+			if tx.InputMint.Equals(p.allAccountKeys[instruction.Accounts[mintX]]) {
+				tx.OutputMint = p.allAccountKeys[instruction.Accounts[mintY]]
+				tx.OutputMintDecimals = p.splTokenInfoMap[p.allAccountKeys[instruction.Accounts[poolOutAccountIndex]].String()].Decimals
+			} else {
+				tx.OutputMint = p.allAccountKeys[instruction.Accounts[mintX]]
+				tx.OutputMintDecimals = p.splTokenInfoMap[p.allAccountKeys[instruction.Accounts[poolInAccountIndex]].String()].Decimals
+			}
+		}
+	}
 
-	poolInBalance, ok := p.postBalance[poolInAccountIndex]
-	if !ok {
-		err = errors.New("no postBalance for account")
+	// vaults marked as lamport vaults hold native SOL without a token balance
+	vaultBalance := func(idx uint16) (*rpc.TokenBalance, error) {
+		accountIndex := instruction.Accounts[idx]
+		if balance, ok := p.postBalance[accountIndex]; ok {
+			return balance, nil
+		}
+		if space, ok := descriptor.lamportVault(int(idx), accLen); ok && tx.hasNativeSOL() {
+			if balance, ok := p.lamportBalance(accountIndex, space); ok {
+				if tx.lamportVaults == nil {
+					tx.lamportVaults = make(map[solana.PublicKey]uint64)
+				}
+				tx.lamportVaults[p.allAccountKeys[accountIndex]] = space
+				return balance, nil
+			}
+		}
+		return nil, fmt.Errorf("%w %s", ErrMissingPostBalance, p.allAccountKeys[accountIndex])
+	}
+	poolInBalance, err := vaultBalance(poolInAccountIndex)
+	if err != nil {
 		return
 	}
-	poolOutBalance, ok := p.postBalance[poolOutAccountIndex]
-	if !ok {
-		err = errors.New("no postBalance for account")
+	poolOutBalance, err := vaultBalance(poolOutAccountIndex)
+	if err != nil {
 		return
 	}
+	poolInAccountIndex = instruction.Accounts[poolInAccountIndex]
+	poolOutAccountIndex = instruction.Accounts[poolOutAccountIndex]
 
 	if poolInBalance.Mint.Equals(tx.OutputMint) {
 		poolInAccountIndex, poolOutAccountIndex = poolOutAccountIndex, poolInAccountIndex
 		poolInBalance, poolOutBalance = poolOutBalance, poolInBalance
 	}
 	if !poolInBalance.Mint.Equals(tx.InputMint) {
-		err = errors.New("no inputMint for account")
+		err = fmt.Errorf("%w: pool account holds %s, input mint is %s", ErrMintMismatch, poolInBalance.Mint, tx.InputMint)
 		return
 	}
 	if !poolOutBalance.Mint.Equals(tx.OutputMint) {
-		err = errors.New("no outputMint for account")
+		err = fmt.Errorf("%w: pool account holds %s, output mint is %s", ErrMintMismatch, poolOutBalance.Mint, tx.OutputMint)
 		return
 	}
 
@@ -1057,7 +931,7 @@ func (p *Parser) setTxPoolInfo(progID solana.PublicKey, tx *TxInfo, instruction
 	}
 	tx.OutputMintDecimals = poolOutBalance.UiTokenAmount.Decimals
 
-	tx.Protocol = protocol
+	tx.Protocol = descriptor.Protocol
 	return
 }
 
@@ -0,0 +1,77 @@
+package solanaswapgo
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// EventID identifies the leg executed by an instruction of a transaction:
+// "<signature>:<outer>" for an outer instruction, "<signature>:<outer>.<inner>"
+// for an inner one. It is stable across parses and nodes, so streaming
+// consumers can deduplicate on it.
+func EventID(signature solana.Signature, outer, inner int) string {
+	if inner < 0 {
+		return fmt.Sprintf("%s:%d", signature, outer)
+	}
+	return fmt.Sprintf("%s:%d.%d", signature, outer, inner)
+}
+
+// setPosition places the leg at the instruction that executed it, inner is
+// -1 for an outer instruction.
+func (tx *TxInfo) setPosition(outer, inner int) {
+	tx.OuterIndex = outer
+	tx.InnerIndex = inner
+	tx.Index = uint(outer*256 + max(inner, 0))
+	tx.positioned = true
+}
+
+// stackHeight returns the invocation depth of an instruction, 1 for an outer
+// one and 0 when the node did not report it.
+func (p *Parser) stackHeight(outer, inner int) int {
+	if inner < 0 {
+		return 1
+	}
+	for _, set := range p.txMeta.InnerInstructions {
+		if int(set.Index) == outer && inner < len(set.Instructions) {
+			return int(set.Instructions[inner].StackHeight)
+		}
+	}
+	return 0
+}
+
+// identify stamps the legs with the transaction and block they come from
+// and gives each an EventID. Legs a handler did not place are put at the
+// outer instruction they were parsed from. Two legs of one instruction get
+// a "#n" suffix in their order.
+func (p *Parser) identify(swaps []SwapData) {
+	var signature solana.Signature
+	if len(p.txInfo.Signatures) > 0 {
+		signature = p.txInfo.Signatures[0]
+	}
+	seen := make(map[*TxInfo]bool)
+	ids := make(map[string]int)
+	for _, swap := range swaps {
+		tx := swap.Tx
+		if tx == nil || seen[tx] {
+			continue
+		}
+		seen[tx] = true
+		if !tx.positioned {
+			tx.setPosition(swap.outer, -1)
+		}
+		tx.Signature = signature
+		tx.Slot = p.slot
+		tx.BlockTime = p.blockTime
+		tx.TxIndexInBlock = p.txIndex
+		tx.StackHeight = p.stackHeight(tx.OuterIndex, tx.InnerIndex)
+
+		id := EventID(signature, tx.OuterIndex, tx.InnerIndex)
+		if n := ids[id]; n > 0 {
+			tx.EventID = fmt.Sprintf("%s#%d", id, n)
+		} else {
+			tx.EventID = id
+		}
+		ids[id]++
+	}
+}
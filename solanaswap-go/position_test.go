@@ -0,0 +1,76 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+// buildRoutedSwap is a Raydium CPMM swap called by a trading bot, after a
+// compute budget instruction.
+func buildRoutedSwap(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "routed-swap")
+	pool := b.account("pool")
+	authority := b.account("authority")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+
+	b.addOuter(solana.ComputeBudget, nil, computeUnitLimitData(300_000))
+	ix := b.addOuter(solanaswapgo.AXIOM_TRADE_PROGRAM_ID, []solana.PublicKey{b.signer}, []byte{1})
+	b.addInner(ix, 2, solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userIn, userOut, vaultIn, vaultOut,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(ix, 3, userIn, vaultIn, b.signer, 25_000_000)
+	b.addTransfer(ix, 3, vaultOut, userOut, authority, 61_234_567_890)
+
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 100_000_000, 75_000_000)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 61_234_567_890)
+	b.tokenAccount(vaultIn, syntheticUSDC, authority, 6, 5_000_000_000, 5_025_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, authority, 6, 12_000_000_000_000, 11_938_765_432_110)
+	b.lamports(b.signer, 2_000_000_000, 1_999_995_000)
+	return b
+}
+
+func TestLegPosition(t *testing.T) {
+	b := buildRoundTrip(t)
+	legs, err := parseWithConfig(t, b, solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Len(t, legs, 2)
+	signature := solana.MustSignatureFromBase58(b.signature())
+	for i, leg := range legs {
+		require.Equal(t, signature, leg.Tx.Signature)
+		require.Equal(t, uint64(300_000_000), leg.Tx.Slot)
+		require.Equal(t, -1, leg.Tx.TxIndexInBlock)
+		require.Equal(t, i, leg.Tx.OuterIndex)
+		require.Equal(t, -1, leg.Tx.InnerIndex)
+		require.Equal(t, 1, leg.Tx.StackHeight)
+		require.Equal(t, solanaswapgo.EventID(signature, i, -1), leg.Tx.EventID)
+	}
+	require.Equal(t, b.signature()+":1", legs[1].Tx.EventID)
+
+	b = buildRoutedSwap(t)
+	legs, err = parseWithConfig(t, b, solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	tx := legs[0].Tx
+	require.Equal(t, 1, tx.OuterIndex)
+	require.Equal(t, 0, tx.InnerIndex)
+	require.Equal(t, 2, tx.StackHeight)
+	require.Equal(t, b.signature()+":1.0", tx.EventID)
+
+	// the same leg parsed from a block knows its place in it
+	blockTx, err := b.result().Transaction.GetTransaction()
+	require.NoError(t, err)
+	results := solanaswapgo.ParseTransactions([]solanaswapgo.BlockTransaction{
+		{Slot: 300_000_001, TxIndex: 42, Transaction: blockTx, Meta: b.result().Meta},
+	}, solanaswapgo.NewParserConfig())
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	tx = results[0].Swaps[0].Tx
+	require.Equal(t, 42, tx.TxIndexInBlock)
+	require.Equal(t, uint64(300_000_001), tx.Slot)
+	require.Equal(t, b.signature()+":1.0", tx.EventID)
+}
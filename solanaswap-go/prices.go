@@ -0,0 +1,98 @@
+package solanaswapgo
+
+import (
+	"math/big"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+)
+
+var basisPoints = decimal.NewFromInt(10_000)
+
+// tokens converts an amount in base units to whole tokens.
+func tokens(amount *big.Int, decimals uint8) decimal.Decimal {
+	return decimal.NewFromBigInt(amount, -int32(decimals))
+}
+
+// price returns out/in, nil when in is zero.
+func price(in, out decimal.Decimal) *decimal.Decimal {
+	if in.Sign() <= 0 || out.Sign() < 0 {
+		return nil
+	}
+	p := out.Div(in)
+	return &p
+}
+
+// setReserves fills the reserves of the pool vaults before the transaction
+// from the pre-balances of the same accounts.
+func (p *Parser) setReserves(tx *TxInfo) {
+	if tx.PoolInPreAmount == nil && !tx.PoolIn.IsZero() {
+		tx.PoolInPreAmount = p.preReserve(tx, tx.PoolIn)
+	}
+	if tx.PoolOutPreAmount == nil && !tx.PoolOut.IsZero() {
+		tx.PoolOutPreAmount = p.preReserve(tx, tx.PoolOut)
+	}
+}
+
+// preReserve returns the balance of a vault before the transaction, in
+// lamports above the rent-exempt minimum for a lamport vault such as a
+// bonding curve.
+func (p *Parser) preReserve(tx *TxInfo, vault solana.PublicKey) *big.Int {
+	i, ok := p.accountIndex(vault)
+	if !ok {
+		return nil
+	}
+	if balance, ok := p.preBalance[uint16(i)]; ok && balance.UiTokenAmount != nil {
+		amount, ok := new(big.Int).SetString(balance.UiTokenAmount.Amount, 10)
+		if !ok {
+			return nil
+		}
+		return amount
+	}
+	if _, ok := p.postBalance[uint16(i)]; ok {
+		// a token account opened by the transaction held nothing
+		return new(big.Int)
+	}
+	if space, ok := tx.lamportVaults[vault]; ok && i < len(p.txMeta.PreBalances) {
+		return new(big.Int).SetUint64(lamportReserve(p.txMeta.PreBalances[i], space))
+	}
+	return nil
+}
+
+// setPrices computes the prices of the leg from its amounts and the
+// reserves of its pool. Intended legs executed nothing and have no
+// execution price.
+func (tx *TxInfo) setPrices() {
+	tx.ExecutionPrice = nil
+	if tx.Method != MethodIntended {
+		tx.ExecutionPrice = price(
+			tokens(new(big.Int).SetUint64(tx.InputAmount), tx.InputMintDecimals),
+			tokens(new(big.Int).SetUint64(tx.OutputAmount), tx.OutputMintDecimals),
+		)
+	}
+	tx.SpotPriceBefore = tx.spotPrice(tx.PoolInPreAmount, tx.PoolOutPreAmount)
+	tx.SpotPriceAfter = tx.spotPrice(tx.PoolInAmount, tx.PoolOutAmount)
+	tx.PriceImpactBps = nil
+	if tx.SpotPriceBefore != nil && tx.SpotPriceAfter != nil && tx.SpotPriceBefore.Sign() > 0 {
+		// 1 - after/before in one division, the decimals cancel out
+		before := decimal.NewFromBigInt(new(big.Int).Mul(tx.PoolOutPreAmount, tx.PoolInAmount), 0)
+		after := decimal.NewFromBigInt(new(big.Int).Mul(tx.PoolOutAmount, tx.PoolInPreAmount), 0)
+		impact := before.Sub(after).Mul(basisPoints).Div(before)
+		tx.PriceImpactBps = &impact
+	}
+}
+
+func (tx *TxInfo) spotPrice(in, out *big.Int) *decimal.Decimal {
+	if in == nil || out == nil {
+		return nil
+	}
+	return price(tokens(in, tx.InputMintDecimals), tokens(out, tx.OutputMintDecimals))
+}
+
+// setPrice computes the effective price of the swap.
+func (s *SwapInfo) setPrice() {
+	s.Price = price(
+		tokens(new(big.Int).SetUint64(s.TokenInAmount), s.TokenInDecimals),
+		tokens(new(big.Int).SetUint64(s.TokenOutAmount), s.TokenOutDecimals),
+	)
+}
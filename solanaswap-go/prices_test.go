@@ -0,0 +1,62 @@
+package solanaswapgo_test
+
+import (
+	"math/big"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func requireDecimal(t *testing.T, expected string, actual *decimal.Decimal) {
+	t.Helper()
+	require.NotNil(t, actual)
+	require.True(t, decimal.RequireFromString(expected).Equal(*actual), "expected %s, got %s", expected, actual)
+}
+
+func TestPrices(t *testing.T) {
+	parser, err := solanaswapgo.NewParserWithConfig(buildRaydiumCPMMSwap(t).result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	tx := legs[0].Tx
+	require.Equal(t, big.NewInt(5_000_000_000), tx.PoolInPreAmount)
+	require.Equal(t, big.NewInt(12_000_000_000_000), tx.PoolOutPreAmount)
+	require.Equal(t, big.NewInt(5_025_000_000), tx.PoolInAmount)
+	// 61_234.56789 tokens for 25 USDC
+	requireDecimal(t, "2449.3827156", tx.ExecutionPrice)
+	requireDecimal(t, "2400", tx.SpotPriceBefore)
+	requireDecimal(t, "2375.8737178328358209", tx.SpotPriceAfter)
+	requireDecimal(t, "100.5261756965174129", tx.PriceImpactBps)
+
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	requireDecimal(t, "2449.3827156", info.Price)
+
+	// the bonding curve holds lamports, its reserves are its lamport
+	// balances above the rent-exempt minimum
+	b, program := buildBondingCurveBuy(t)
+	registry := solanaswapgo.NewAMMRegistry()
+	require.NoError(t, registry.Register(solanaswapgo.AMMDescriptor{
+		ProgramID:     program,
+		Protocol:      "Curve",
+		Accounts:      solanaswapgo.AMMAccounts{Pool: 1, PoolIn: 1, PoolOut: 2},
+		LamportVaults: []solanaswapgo.AMMLamportVault{{Account: 1}},
+	}))
+	legs, err = parseWithConfig(t, b, solanaswapgo.NewParserConfig(
+		solanaswapgo.WithRegistry(registry),
+		solanaswapgo.WithHandlers(solanaswapgo.NewProtocolHandler("curve", solanaswapgo.RoleAMM, []solana.PublicKey{program},
+			func(ctx *solanaswapgo.ProtocolContext) []solanaswapgo.SwapData {
+				return ctx.ParseTransfers("Curve")
+			})),
+	))
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	tx = legs[0].Tx
+	require.Equal(t, big.NewInt(29_999_109_120), tx.PoolInPreAmount)
+	requireDecimal(t, "3000000", tx.ExecutionPrice)
+	requireDecimal(t, "26667458.5835167627804542", tx.SpotPriceBefore)
+	require.True(t, tx.PriceImpactBps.IsPositive())
+}
@@ -0,0 +1,275 @@
+package solanaswapgo
+
+import (
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ProtocolRole tells ParseTransaction how a handler takes part in dispatch.
+type ProtocolRole int
+
+const (
+	// RoleAMM handlers parse a single pool swap. They run on outer
+	// instructions when no aggregator matched, and on the inner instructions
+	// of routers through ProtocolContext.ParseInner.
+	RoleAMM ProtocolRole = iota
+	// RoleRouter handlers run on outer instructions before AMMs and usually
+	// delegate to ParseInner. Outer AMM instructions are still parsed.
+	RoleRouter
+	// RoleAggregator handlers run like routers, but once one matches the
+	// outer AMM pass is skipped for the whole transaction.
+	RoleAggregator
+)
+
+// ProtocolHandler parses the swaps of one DEX, router or aggregator.
+//
+// Handlers are tried in registration order and the first one whose Match
+// returns true parses the instruction.
+type ProtocolHandler interface {
+	Name() string
+	Role() ProtocolRole
+	Match(progID solana.PublicKey, ix solana.CompiledInstruction) bool
+	Parse(ctx *ProtocolContext) []SwapData
+}
+
+// ProtocolContext is the instruction being parsed by a ProtocolHandler.
+type ProtocolContext struct {
+	ProgramID   solana.PublicKey
+	Instruction solana.CompiledInstruction
+	OuterIndex  int
+	// InnerIndex is the position inside the inner instructions of OuterIndex,
+	// or -1 for outer instructions.
+	InnerIndex int
+	IsInner    bool
+
+	parser    *Parser
+	processed map[string]bool
+	diag      *InstructionDiagnostic
+}
+
+// Once reports whether key is seen for the first time among the inner
+// instructions of the current outer instruction. Handlers that parse all
+// inner instructions of a router at once use it to avoid duplicate legs.
+func (c *ProtocolContext) Once(key string) bool {
+	if c.processed[key] {
+		if c.diag != nil {
+			c.diag.SkipReason = SkipReasonAlreadyProcessed
+		}
+		return false
+	}
+	c.processed[key] = true
+	return true
+}
+
+// ParseInner dispatches the inner instructions of the outer instruction to
+// the AMM handlers.
+func (c *ProtocolContext) ParseInner() []SwapData {
+	return c.parser.processRouterSwaps(c.OuterIndex)
+}
+
+// ParseTransfers builds a leg from the token transfers that follow the
+// instruction, stopping at the next known AMM, and enriches it with the pool
+// info of the program's AMMDescriptor.
+func (c *ProtocolContext) ParseTransfers(swapType SwapType) []SwapData {
+	return c.parser.processTransferSwaps(c.ProgramID, swapType, c.OuterIndex, c.InnerIndex, &c.Instruction)
+}
+
+// Logger returns the parser's logger, scoped to the instruction and handler.
+func (c *ProtocolContext) Logger() Logger {
+	return c.parser.log
+}
+
+func (c *ProtocolContext) AccountKeys() solana.PublicKeySlice {
+	return c.parser.allAccountKeys
+}
+
+// InnerInstructions returns the inner instructions of the outer instruction.
+func (c *ProtocolContext) InnerInstructions() []solana.CompiledInstruction {
+	return c.parser.getInnerInstructions(c.OuterIndex)
+}
+
+func (c *ProtocolContext) Transaction() *solana.Transaction {
+	return c.parser.txInfo
+}
+
+func (c *ProtocolContext) Meta() *rpc.TransactionMeta {
+	return c.parser.txMeta
+}
+
+type protocolHandler struct {
+	name     string
+	role     ProtocolRole
+	programs []solana.PublicKey
+	parse    func(ctx *ProtocolContext) []SwapData
+}
+
+// NewProtocolHandler returns a handler that matches instructions of the
+// given programs.
+func NewProtocolHandler(name string, role ProtocolRole, programs []solana.PublicKey, parse func(ctx *ProtocolContext) []SwapData) ProtocolHandler {
+	return &protocolHandler{name: name, role: role, programs: programs, parse: parse}
+}
+
+func (h *protocolHandler) Name() string       { return h.name }
+func (h *protocolHandler) Role() ProtocolRole { return h.role }
+
+func (h *protocolHandler) Match(progID solana.PublicKey, ix solana.CompiledInstruction) bool {
+	for _, prog := range h.programs {
+		if progID.Equals(prog) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *protocolHandler) Parse(ctx *ProtocolContext) []SwapData {
+	return h.parse(ctx)
+}
+
+// routerHandler delegates every one of its programs to ParseInner.
+type routerHandler struct {
+	programs []solana.PublicKey
+}
+
+func (routerHandler) Name() string       { return "router" }
+func (routerHandler) Role() ProtocolRole { return RoleRouter }
+
+func (h routerHandler) Match(progID solana.PublicKey, ix solana.CompiledInstruction) bool {
+	for _, prog := range h.programs {
+		if progID.Equals(prog) {
+			return true
+		}
+	}
+	return false
+}
+
+func (routerHandler) Parse(ctx *ProtocolContext) []SwapData {
+	return ctx.ParseInner()
+}
+
+// configRouters holds the place of the router handler among the built-in
+// ones. NewParserConfig replaces it with a routerHandler of the router
+// programs of the config.
+type configRouters struct{}
+
+func (configRouters) Name() string                                            { return "router" }
+func (configRouters) Role() ProtocolRole                                      { return RoleRouter }
+func (configRouters) Match(solana.PublicKey, solana.CompiledInstruction) bool { return false }
+func (configRouters) Parse(*ProtocolContext) []SwapData                       { return nil }
+
+func defaultProtocolHandlers() []ProtocolHandler {
+	return []ProtocolHandler{
+		NewProtocolHandler("jupiter", RoleAggregator, []solana.PublicKey{JUPITER_PROGRAM_ID, DFLOW_AGGREGATOR_V4}, func(ctx *ProtocolContext) []SwapData {
+			if swaps := ctx.parser.processJupiterSwaps(ctx.OuterIndex); len(swaps) > 0 {
+				return swaps
+			}
+			// Fallback: RouteV2 or other newer Jupiter instructions may not emit
+			// JupiterRouteEvent. In that case scan inner instructions like a
+			// normal router.
+			return ctx.ParseInner()
+		}),
+		NewProtocolHandler("moonshot", RoleAggregator, []solana.PublicKey{MOONSHOT_PROGRAM_ID}, func(ctx *ProtocolContext) []SwapData {
+			return ctx.parser.processMoonshotSwaps(ctx.OuterIndex)
+		}),
+		configRouters{},
+		NewProtocolHandler("okx", RoleAggregator, []solana.PublicKey{OKX_DEX_ROUTER_PROGRAM_ID}, func(ctx *ProtocolContext) []SwapData {
+			return ctx.parser.processOKXSwaps(ctx.OuterIndex)
+		}),
+		NewProtocolHandler("raydium_router", RoleAggregator, []solana.PublicKey{RAYDIUM_AMM_ROUTER_PROGRAM_ID}, func(ctx *ProtocolContext) []SwapData {
+			return ctx.parser.processRaydSwaps(RAYDIUM_AMM_ROUTER_PROGRAM_ID, ctx.OuterIndex, 0, &ctx.Instruction, true)
+		}),
+
+		NewProtocolHandler(PROTOCOL_RAYDIUM, RoleAMM, []solana.PublicKey{
+			RAYDIUM_V4_PROGRAM_ID,
+			RAYDIUM_CPMM_PROGRAM_ID,
+			RAYDIUM_CONCENTRATED_LIQUIDITY_PROGRAM_ID,
+			RAYDIUM_LAUNCHLAB_PROGRAM_ID,
+		}, func(ctx *ProtocolContext) []SwapData {
+			if !ctx.IsInner {
+				return ctx.parser.processRaydSwaps(ctx.ProgramID, ctx.OuterIndex, 0, &ctx.Instruction, false)
+			}
+			if ctx.ProgramID.Equals(RAYDIUM_LAUNCHLAB_PROGRAM_ID) || !ctx.Once(PROTOCOL_RAYDIUM) {
+				return nil
+			}
+			return ctx.parser.processRaydSwaps(ctx.ProgramID, ctx.OuterIndex, ctx.InnerIndex, &ctx.Instruction, true)
+		}),
+		NewProtocolHandler(PROTOCOL_ORCA, RoleAMM, []solana.PublicKey{ORCA_PROGRAM_ID}, func(ctx *ProtocolContext) []SwapData {
+			if ctx.IsInner && !ctx.Once(PROTOCOL_ORCA) {
+				return nil
+			}
+			return ctx.parser.processOrcaSwaps(ctx.OuterIndex)
+		}),
+		NewProtocolHandler(PROTOCOL_METEORA, RoleAMM, []solana.PublicKey{
+			METEORA_PROGRAM_ID,
+			METEORA_POOLS_PROGRAM_ID,
+			METEORA_DLMM_PROGRAM_ID,
+			Meteora_Dynamic_Bonding_Curve_Program,
+			METEORA_DAMM_V2,
+		}, func(ctx *ProtocolContext) []SwapData {
+			if !ctx.IsInner {
+				if swaps := ctx.parser.processMeteoraSwaps(ctx.ProgramID, ctx.OuterIndex, 0, false); len(swaps) > 0 {
+					return swaps
+				}
+				// Fallback: some Meteora programs act as routers (e.g. King7ki... DLMM router)
+				return ctx.ParseInner()
+			}
+			if ctx.ProgramID.Equals(Meteora_Dynamic_Bonding_Curve_Program) || !ctx.Once(PROTOCOL_METEORA) {
+				return nil
+			}
+			return ctx.parser.processMeteoraSwaps(ctx.ProgramID, ctx.OuterIndex, ctx.InnerIndex, true)
+		}),
+		NewProtocolHandler(PROTOCOL_PUMPFUN, RoleAMM, []solana.PublicKey{
+			PUMPFUN_AMM_PROGRAM_ID,
+			PUMP_FUN_PROGRAM_ID,
+			PUMP_FUN_BSFD_PROGRAM_ID,
+		}, func(ctx *ProtocolContext) []SwapData {
+			if ctx.IsInner && !ctx.Once(PROTOCOL_PUMPFUN) {
+				return nil
+			}
+			if ctx.ProgramID.Equals(PUMPFUN_AMM_PROGRAM_ID) {
+				return ctx.parser.processPumpfunAMMSwaps(ctx.OuterIndex, ctx.IsInner)
+			}
+			return ctx.parser.processPumpfunSwaps(ctx.OuterIndex)
+		}),
+		NewProtocolHandler("zerofi", RoleAMM, []solana.PublicKey{ZEROFI}, func(ctx *ProtocolContext) []SwapData {
+			return ctx.parser.processZerofiSwaps(ctx.OuterIndex, ctx.IsInner)
+		}),
+		NewProtocolHandler("humidifi", RoleAMM, []solana.PublicKey{HUMIDIDI_PROGRAM_ID}, func(ctx *ProtocolContext) []SwapData {
+			return ctx.parser.processHumidifiSwaps(ctx.OuterIndex, ctx.InnerIndex, &ctx.Instruction)
+		}),
+	}
+}
+
+var (
+	protocolMu       sync.RWMutex
+	protocolHandlers = defaultProtocolHandlers()
+)
+
+// RegisterProtocol adds a handler in front of the built-in ones, so it can
+// also take over programs that are already supported. Configs created
+// afterwards use it.
+func RegisterProtocol(h ProtocolHandler) {
+	protocolMu.Lock()
+	defer protocolMu.Unlock()
+	protocolHandlers = append([]ProtocolHandler{h}, protocolHandlers...)
+}
+
+func registeredProtocols() []ProtocolHandler {
+	protocolMu.RLock()
+	defer protocolMu.RUnlock()
+	return protocolHandlers
+}
+
+// matchProtocol returns the first handler with one of the roles that
+// matches the instruction.
+func (p *Parser) matchProtocol(progID solana.PublicKey, ix solana.CompiledInstruction, roles ...ProtocolRole) ProtocolHandler {
+	for _, h := range p.config.protocols {
+		for _, role := range roles {
+			if h.Role() == role && h.Match(progID, ix) {
+				return h
+			}
+		}
+	}
+	return nil
+}
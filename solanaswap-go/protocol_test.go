@@ -0,0 +1,110 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterProtocol(t *testing.T) {
+	b := newTxBuilder(t, "custom-dex")
+	program := b.account("program")
+	pool := b.account("pool")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+
+	require.NoError(t, solanaswapgo.RegisterAMM(solanaswapgo.AMMDescriptor{
+		ProgramID:        program,
+		Protocol:         "MyDex",
+		DiscriminatorLen: 1,
+		Discriminators:   []string{"2a"},
+		Accounts:         solanaswapgo.AMMAccounts{Pool: 0, PoolIn: 3, PoolOut: 4},
+	}))
+	solanaswapgo.RegisterProtocol(solanaswapgo.NewProtocolHandler("mydex", solanaswapgo.RoleAMM, []solana.PublicKey{program},
+		func(ctx *solanaswapgo.ProtocolContext) []solanaswapgo.SwapData {
+			return ctx.ParseTransfers("MyDex")
+		}))
+
+	ix := b.addOuter(program, []solana.PublicKey{pool, userIn, userOut, vaultIn, vaultOut, b.signer}, []byte{0x2a, 1, 2, 3})
+	b.addTransfer(ix, 2, userIn, vaultIn, b.signer, 10_000_000)
+	b.addTransfer(ix, 2, vaultOut, userOut, pool, 7_000_000_000)
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 10_000_000, 0)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 7_000_000_000)
+	b.tokenAccount(vaultIn, syntheticUSDC, pool, 6, 90_000_000, 100_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, pool, 6, 70_000_000_000, 63_000_000_000)
+
+	parser, err := solanaswapgo.NewParser(b.result())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+
+	tx := legs[0].Tx
+	require.Equal(t, "MyDex", tx.Protocol)
+	require.Equal(t, syntheticUSDC, tx.InputMint)
+	require.Equal(t, uint64(10_000_000), tx.InputAmount)
+	require.Equal(t, syntheticMint, tx.OutputMint)
+	require.Equal(t, uint64(7_000_000_000), tx.OutputAmount)
+	require.Equal(t, pool, tx.Pool)
+	require.Equal(t, vaultIn, tx.PoolIn)
+	require.Equal(t, vaultOut, tx.PoolOut)
+}
+
+// buildRaydiumOrcaRoute routes USDC into a token on Raydium CPMM and back
+// into USDC on Orca through a router, all in one outer instruction.
+func buildRaydiumOrcaRoute(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "raydium-orca-route")
+	router := b.account("router")
+	pool, authority := b.account("pool"), b.account("authority")
+	userUSDC, userToken := b.account("user-usdc"), b.account("user-token")
+	vaultUSDC, vaultToken := b.account("vault-usdc"), b.account("vault-token")
+	whirlpool := b.account("whirlpool")
+	orcaUSDC, orcaToken := b.account("orca-usdc"), b.account("orca-token")
+
+	ix := b.addOuter(router, []solana.PublicKey{b.signer}, []byte{1})
+	b.addInner(ix, 2, solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userUSDC, userToken, vaultUSDC, vaultToken,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(ix, 3, userUSDC, vaultUSDC, b.signer, 25_000_000)
+	b.addTransfer(ix, 3, vaultToken, userToken, authority, 61_234_567_890)
+	b.addInner(ix, 2, solanaswapgo.ORCA_PROGRAM_ID, []solana.PublicKey{
+		solana.TokenProgramID, b.signer, whirlpool, userToken, orcaToken, userUSDC, orcaUSDC,
+	}, append(anchorDiscriminator("swap"), make([]byte, 16)...))
+	b.addTransfer(ix, 3, userToken, orcaToken, b.signer, 61_234_567_890)
+	b.addTransfer(ix, 3, orcaUSDC, userUSDC, whirlpool, 25_100_000)
+
+	b.tokenAccount(userUSDC, syntheticUSDC, b.signer, 6, 100_000_000, 100_100_000)
+	b.tokenAccount(userToken, syntheticMint, b.signer, 6, 0, 0)
+	b.tokenAccount(vaultUSDC, syntheticUSDC, authority, 6, 5_000_000_000, 5_025_000_000)
+	b.tokenAccount(vaultToken, syntheticMint, authority, 6, 12_000_000_000_000, 11_938_765_432_110)
+	b.tokenAccount(orcaUSDC, syntheticUSDC, whirlpool, 6, 9_000_000_000, 8_974_900_000)
+	b.tokenAccount(orcaToken, syntheticMint, whirlpool, 6, 20_000_000_000_000, 20_061_234_567_890)
+	b.lamports(b.signer, 2_000_000_000, 1_999_995_000)
+	return b
+}
+
+func TestTransferBoundary(t *testing.T) {
+	b := buildRaydiumOrcaRoute(t)
+	router := b.account("router")
+	for _, config := range []*solanaswapgo.ParserConfig{
+		solanaswapgo.NewParserConfig(solanaswapgo.WithRouterPrograms(router)),
+		// Orca is disabled but still ends the Raydium leg
+		solanaswapgo.NewParserConfig(solanaswapgo.WithRouterPrograms(router), solanaswapgo.WithProtocols("router", solanaswapgo.PROTOCOL_RAYDIUM)),
+	} {
+		legs, err := parseWithConfig(t, b, config)
+		require.NoError(t, err)
+		var raydium *solanaswapgo.TxInfo
+		for _, leg := range legs {
+			if leg.Tx != nil && leg.Tx.Amm.Equals(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID) {
+				raydium = leg.Tx
+			}
+		}
+		require.NotNil(t, raydium)
+		require.Equal(t, syntheticUSDC, raydium.InputMint)
+		require.Equal(t, uint64(25_000_000), raydium.InputAmount)
+		require.Equal(t, uint64(61_234_567_890), raydium.OutputAmount)
+	}
+}
@@ -0,0 +1,181 @@
+package solanaswapgo
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Hop is one swap of a route, from one mint node to another.
+type Hop struct {
+	// Index is the execution order, outer instruction * 256 + inner
+	// instruction like TxInfo.Index.
+	Index          uint
+	AMM            string
+	Program        solana.PublicKey
+	Pool           solana.PublicKey
+	InputMint      solana.PublicKey
+	InputAmount    uint64
+	InputDecimals  uint8
+	OutputMint     solana.PublicKey
+	OutputAmount   uint64
+	OutputDecimals uint8
+	// Split is the share of everything that left InputMint in the route
+	// that went through this hop.
+	Split float64
+}
+
+// Route is the graph of the hops of a swap: mints are the nodes and hops the
+// edges. Split routes such as SOL→A→USDC plus SOL→B→USDC have two paths
+// between the same input and output.
+type Route struct {
+	// Hops in execution order.
+	Hops []Hop
+	// Mints in the order they are first reached.
+	Mints []solana.PublicKey
+
+	InputMint      solana.PublicKey
+	InputAmount    uint64
+	InputDecimals  uint8
+	OutputMint     solana.PublicKey
+	OutputAmount   uint64
+	OutputDecimals uint8
+}
+
+// AMMs returns the names of the AMMs of the hops, each once.
+func (r *Route) AMMs() []string {
+	var amms []string
+	seen := make(map[string]bool)
+	for _, hop := range r.Hops {
+		if !seen[hop.AMM] {
+			seen[hop.AMM] = true
+			amms = append(amms, hop.AMM)
+		}
+	}
+	return amms
+}
+
+// setRoute takes the amounts and AMMs of the swap from route.
+func (s *SwapInfo) setRoute(route *Route) {
+	s.Route = route
+	s.TokenInMint = route.InputMint
+	s.TokenInAmount = route.InputAmount
+	s.TokenInDecimals = route.InputDecimals
+	s.TokenOutMint = route.OutputMint
+	s.TokenOutAmount = route.OutputAmount
+	s.TokenOutDecimals = route.OutputDecimals
+	s.AMMs = route.AMMs()
+}
+
+// hop returns the hop of a leg, false for legs that do not swap such as
+// plain transfers.
+func (p *Parser) hop(swap SwapData) (Hop, bool) {
+	if tx := swap.Tx; tx != nil {
+		hop := Hop{
+			Index:          tx.Index,
+			AMM:            tx.Protocol,
+			Program:        tx.Amm,
+			Pool:           tx.Pool,
+			InputMint:      tx.InputMint,
+			InputAmount:    tx.InputAmount,
+			InputDecimals:  tx.InputMintDecimals,
+			OutputMint:     tx.OutputMint,
+			OutputAmount:   tx.OutputAmount,
+			OutputDecimals: tx.OutputMintDecimals,
+		}
+		if hop.AMM == "" {
+			hop.AMM = string(swap.Type)
+		}
+		return hop, true
+	}
+
+	// an event without its AMM instruction is placed at the event, which
+	// follows the instruction it reports
+	event, ok := swap.Data.(*JupiterSwapEventData)
+	if !ok {
+		return Hop{}, false
+	}
+	hop := Hop{
+		Index:          uint(swap.outer*256 + swap.inner),
+		AMM:            event.Amm.String(),
+		Program:        event.Amm,
+		InputMint:      event.InputMint,
+		InputAmount:    event.InputAmount,
+		InputDecimals:  event.InputMintDecimals,
+		OutputMint:     event.OutputMint,
+		OutputAmount:   event.OutputAmount,
+		OutputDecimals: event.OutputMintDecimals,
+	}
+	if descriptor, ok := p.config.ammRegistry.Lookup(event.Amm); ok {
+		hop.AMM = descriptor.Protocol
+	}
+	return hop, true
+}
+
+// buildRoute links the hops of the legs by their mints. The input of the
+// route is the mint no hop produces and its output the mint no hop consumes;
+// a cycle such as an arbitrage starts and ends at the input of its first
+// hop. Amounts are summed over every hop leaving the input and reaching the
+// output.
+func (p *Parser) buildRoute(swaps []SwapData) (*Route, error) {
+	route := &Route{}
+	for _, swap := range swaps {
+		if hop, ok := p.hop(swap); ok {
+			route.Hops = append(route.Hops, hop)
+		}
+	}
+	if len(route.Hops) == 0 {
+		return nil, fmt.Errorf("no hops in route")
+	}
+	sort.SliceStable(route.Hops, func(i, j int) bool { return route.Hops[i].Index < route.Hops[j].Index })
+
+	produced := make(map[solana.PublicKey]bool)
+	consumed := make(map[solana.PublicKey]uint64)
+	seen := make(map[solana.PublicKey]bool)
+	for _, hop := range route.Hops {
+		produced[hop.OutputMint] = true
+		consumed[hop.InputMint] += hop.InputAmount
+		for _, mint := range []solana.PublicKey{hop.InputMint, hop.OutputMint} {
+			if !seen[mint] {
+				seen[mint] = true
+				route.Mints = append(route.Mints, mint)
+			}
+		}
+	}
+	isConsumed := func(mint solana.PublicKey) bool {
+		_, ok := consumed[mint]
+		return ok
+	}
+	for i, hop := range route.Hops {
+		if total := consumed[hop.InputMint]; total > 0 {
+			route.Hops[i].Split = float64(hop.InputAmount) / float64(total)
+		}
+	}
+
+	first, last := route.Hops[0], route.Hops[len(route.Hops)-1]
+	route.InputMint, route.InputDecimals = first.InputMint, first.InputDecimals
+	route.OutputMint, route.OutputDecimals = last.OutputMint, last.OutputDecimals
+	for _, hop := range route.Hops {
+		if !produced[hop.InputMint] {
+			route.InputMint, route.InputDecimals = hop.InputMint, hop.InputDecimals
+			break
+		}
+	}
+	for i := len(route.Hops) - 1; i >= 0; i-- {
+		if hop := route.Hops[i]; !isConsumed(hop.OutputMint) {
+			route.OutputMint, route.OutputDecimals = hop.OutputMint, hop.OutputDecimals
+			break
+		}
+	}
+
+	for _, hop := range route.Hops {
+		if hop.InputMint.Equals(route.InputMint) {
+			route.InputAmount += hop.InputAmount
+		}
+		if hop.OutputMint.Equals(route.OutputMint) {
+			route.OutputAmount += hop.OutputAmount
+		}
+	}
+	return route, nil
+}
@@ -0,0 +1,131 @@
+package solanaswapgo_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+var orcaWhirlpool = solana.MustPublicKeyFromBase58("whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc")
+
+func jupiterRouteEventData(amm, inputMint solana.PublicKey, inputAmount uint64, outputMint solana.PublicKey, outputAmount uint64) []byte {
+	data := append([]byte{}, solanaswapgo.JupiterRouteEventDiscriminator[:]...)
+	data = append(data, amm[:]...)
+	data = append(data, inputMint[:]...)
+	data = binary.LittleEndian.AppendUint64(data, inputAmount)
+	data = append(data, outputMint[:]...)
+	return binary.LittleEndian.AppendUint64(data, outputAmount)
+}
+
+// buildSplitRoute swaps SOL to USDC through Jupiter along two paths,
+// SOL→A→USDC and SOL→B→USDC.
+func buildSplitRoute(t testing.TB) (*txBuilder, solana.PublicKey, solana.PublicKey, solana.PublicKey) {
+	b := newTxBuilder(t, "split-route")
+	mintA, mintB, unknownAMM := b.account("mint-a"), b.account("mint-b"), b.account("unknown-amm")
+	sol := solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID
+
+	ix := b.addOuter(solanaswapgo.JUPITER_PROGRAM_ID, []solana.PublicKey{b.signer}, append(anchorDiscriminator("route"), make([]byte, 16)...))
+	event := func(amm, in solana.PublicKey, inAmount uint64, out solana.PublicKey, outAmount uint64) {
+		b.addInner(ix, 2, solanaswapgo.JUPITER_PROGRAM_ID, []solana.PublicKey{b.account("event-authority")}, jupiterRouteEventData(amm, in, inAmount, out, outAmount))
+	}
+	event(orcaWhirlpool, sol, 600_000_000, mintA, 9_000_000)
+	event(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, mintA, 9_000_000, syntheticUSDC, 90_000_000)
+	event(orcaWhirlpool, sol, 400_000_000, mintB, 123_000)
+	event(unknownAMM, mintB, 123_000, syntheticUSDC, 59_000_000)
+	return b, mintA, mintB, unknownAMM
+}
+
+func TestSplitRoute(t *testing.T) {
+	b, mintA, mintB, unknownAMM := buildSplitRoute(t)
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 4)
+
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, info.TokenInMint)
+	require.Equal(t, uint64(1_000_000_000), info.TokenInAmount)
+	require.Equal(t, syntheticUSDC, info.TokenOutMint)
+	require.Equal(t, uint64(149_000_000), info.TokenOutAmount)
+	require.Equal(t, []string{"Orca", "Raydium", unknownAMM.String()}, info.AMMs)
+
+	route := info.Route
+	require.NotNil(t, route)
+	require.Equal(t, []solana.PublicKey{solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, mintA, syntheticUSDC, mintB}, route.Mints)
+	require.Len(t, route.Hops, 4)
+	require.InDelta(t, 0.6, route.Hops[0].Split, 1e-9)
+	require.Equal(t, 1.0, route.Hops[1].Split)
+	require.InDelta(t, 0.4, route.Hops[2].Split, 1e-9)
+	require.Equal(t, orcaWhirlpool, route.Hops[2].Program)
+	require.Equal(t, mintB, route.Hops[3].InputMint)
+}
+
+func TestCyclicRoute(t *testing.T) {
+	b := newTxBuilder(t, "cyclic-route")
+	mintA := b.account("mint-a")
+	sol := solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID
+	ix := b.addOuter(solanaswapgo.JUPITER_PROGRAM_ID, []solana.PublicKey{b.signer}, append(anchorDiscriminator("route"), make([]byte, 16)...))
+	b.addInner(ix, 2, solanaswapgo.JUPITER_PROGRAM_ID, nil, jupiterRouteEventData(orcaWhirlpool, sol, 1_000_000_000, mintA, 5_000_000))
+	b.addInner(ix, 2, solanaswapgo.JUPITER_PROGRAM_ID, nil, jupiterRouteEventData(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, mintA, 5_000_000, sol, 1_010_000_000))
+
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, sol, info.TokenInMint)
+	require.Equal(t, uint64(1_000_000_000), info.TokenInAmount)
+	require.Equal(t, sol, info.TokenOutMint)
+	require.Equal(t, uint64(1_010_000_000), info.TokenOutAmount)
+}
+
+func TestMixedRoute(t *testing.T) {
+	// the first hop is read from its Raydium CPMM instruction, the second
+	// only from its event; the event hop is placed after the first one
+	b := newTxBuilder(t, "mixed-route")
+	pool, authority := b.account("pool"), b.account("authority")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+	mintB, unknownAMM := b.account("mint-b"), b.account("unknown-amm")
+
+	ix := b.addOuter(solanaswapgo.JUPITER_PROGRAM_ID, []solana.PublicKey{b.signer}, append(anchorDiscriminator("route"), make([]byte, 16)...))
+	b.addInner(ix, 2, solana.MemoProgramID, nil, []byte("route"))
+	b.addInner(ix, 2, solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userIn, userOut, vaultIn, vaultOut,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(ix, 3, userIn, vaultIn, b.signer, 25_000_000)
+	b.addTransfer(ix, 3, vaultOut, userOut, authority, 61_234_567_890)
+	b.addInner(ix, 2, solanaswapgo.JUPITER_PROGRAM_ID, nil, jupiterRouteEventData(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, syntheticUSDC, 25_000_000, syntheticMint, 61_234_567_890))
+	b.addInner(ix, 2, solanaswapgo.JUPITER_PROGRAM_ID, nil, jupiterRouteEventData(unknownAMM, syntheticMint, 61_234_567_890, mintB, 7_000))
+
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 100_000_000, 75_000_000)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 0)
+	b.tokenAccount(vaultIn, syntheticUSDC, authority, 6, 5_000_000_000, 5_025_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, authority, 6, 12_000_000_000_000, 11_938_765_432_110)
+
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 2)
+	require.NotNil(t, legs[0].Tx)
+	require.Nil(t, legs[1].Tx)
+
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	hops := info.Route.Hops
+	require.Len(t, hops, 2)
+	require.Equal(t, pool, hops[0].Pool)
+	require.Equal(t, uint(1), hops[0].Index)
+	require.Equal(t, unknownAMM, hops[1].Program)
+	require.Equal(t, uint(5), hops[1].Index)
+	require.Equal(t, syntheticUSDC, info.TokenInMint)
+	require.Equal(t, mintB, info.TokenOutMint)
+}
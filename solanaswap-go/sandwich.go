@@ -0,0 +1,195 @@
+package solanaswapgo
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// BlockSwap is a swap leg placed in the block it landed in.
+type BlockSwap struct {
+	Signature solana.Signature
+	Slot      uint64
+	// TxIndex is the position of the transaction in its block.
+	TxIndex int
+	// Signer is the fee payer of the transaction.
+	Signer solana.PublicKey
+	Leg    *TxInfo
+}
+
+// BlockSwaps returns the legs of the parsed transaction that name a pool,
+// placed at txIndex of slot, for DetectSandwiches.
+func (p *Parser) BlockSwaps(legs []SwapData, slot uint64, txIndex int) []BlockSwap {
+	var swaps []BlockSwap
+	for _, leg := range legs {
+		if leg.Tx == nil || leg.Tx.Pool.IsZero() || leg.Tx.Type != TxTypeSwap {
+			continue
+		}
+		swap := BlockSwap{Slot: slot, TxIndex: txIndex, Signer: p.allAccountKeys[0], Leg: leg.Tx}
+		if len(p.txInfo.Signatures) > 0 {
+			swap.Signature = p.txInfo.Signatures[0]
+		}
+		swaps = append(swaps, swap)
+	}
+	return swaps
+}
+
+// sameTrader reports whether two swaps were made by the same fee payer or
+// for the same owner. Unknown, zero, keys never match.
+func (s BlockSwap) sameTrader(o BlockSwap) bool {
+	same := func(a, b solana.PublicKey) bool { return !a.IsZero() && a.Equals(b) }
+	return same(s.Signer, o.Signer) || same(s.Leg.Owner, o.Leg.Owner)
+}
+
+func (s BlockSwap) before(o BlockSwap) bool {
+	if s.Slot != o.Slot {
+		return s.Slot < o.Slot
+	}
+	return s.TxIndex < o.TxIndex
+}
+
+func (s BlockSwap) sameDirection(o BlockSwap) bool {
+	return s.Leg.InputMint.Equals(o.Leg.InputMint) && s.Leg.OutputMint.Equals(o.Leg.OutputMint)
+}
+
+// SandwichVictim is a swap made between the front-run and the back-run.
+type SandwichVictim struct {
+	Swap BlockSwap
+	// Loss is how much less of its output mint the victim received than
+	// without the front-run, estimated from the reserves of the pool as a
+	// constant product. It is 0 when the reserves are unknown.
+	Loss uint64
+}
+
+// Sandwich is a front-run and a back-run of one attacker on a pool around
+// the swaps of others in the same direction as the front-run.
+type Sandwich struct {
+	Pool     solana.PublicKey
+	Attacker solana.PublicKey
+	FrontRun BlockSwap
+	BackRun  BlockSwap
+	Victims  []SandwichVictim
+	// Profit is what the back-run got out minus what the front-run put in,
+	// in ProfitMint, for the tokens both of them traded: when the back-run
+	// sells less or more than the front-run bought, both sides are scaled to
+	// the smaller amount. Pool fees are included as the amounts are what the
+	// attacker paid and received, transaction fees and tips are not.
+	ProfitMint solana.PublicKey
+	Profit     int64
+	// Unmatched is how much of the front-run output the back-run did not
+	// sell, negative when it sold more than the front-run bought.
+	Unmatched int64
+}
+
+// DetectSandwiches finds sandwiches among the swaps of a block or a range
+// of slots. Every front-run is paired with the first later swap of the same
+// trader on the same pool in the opposite direction that has victims in
+// between, each swap is part of at most one sandwich.
+func DetectSandwiches(swaps []BlockSwap) []Sandwich {
+	byPool := make(map[solana.PublicKey][]BlockSwap)
+	var pools []solana.PublicKey
+	for _, swap := range swaps {
+		if swap.Leg == nil || swap.Leg.Pool.IsZero() {
+			continue
+		}
+		if _, ok := byPool[swap.Leg.Pool]; !ok {
+			pools = append(pools, swap.Leg.Pool)
+		}
+		byPool[swap.Leg.Pool] = append(byPool[swap.Leg.Pool], swap)
+	}
+
+	var sandwiches []Sandwich
+	for _, pool := range pools {
+		legs := byPool[pool]
+		sort.SliceStable(legs, func(i, j int) bool { return legs[i].before(legs[j]) })
+		used := make([]bool, len(legs))
+		for i, front := range legs {
+			if used[i] {
+				continue
+			}
+			for k := i + 1; k < len(legs); k++ {
+				back := legs[k]
+				if used[k] || !back.sameTrader(front) || !front.before(back) ||
+					!back.Leg.InputMint.Equals(front.Leg.OutputMint) || !back.Leg.OutputMint.Equals(front.Leg.InputMint) {
+					continue
+				}
+				var victims []SandwichVictim
+				var victimIndexes []int
+				for j := i + 1; j < k; j++ {
+					victim := legs[j]
+					if used[j] || victim.sameTrader(front) || !front.before(victim) || !victim.before(back) || !victim.sameDirection(front) {
+						continue
+					}
+					victims = append(victims, SandwichVictim{Swap: victim, Loss: victimLoss(front.Leg, victim.Leg)})
+					victimIndexes = append(victimIndexes, j)
+				}
+				if len(victims) == 0 {
+					continue
+				}
+				used[i], used[k] = true, true
+				for _, j := range victimIndexes {
+					used[j] = true
+				}
+				sandwiches = append(sandwiches, Sandwich{
+					Pool:       pool,
+					Attacker:   front.Signer,
+					FrontRun:   front,
+					BackRun:    back,
+					Victims:    victims,
+					ProfitMint: front.Leg.InputMint,
+					Profit:     sandwichProfit(front.Leg, back.Leg),
+					Unmatched:  int64(front.Leg.OutputAmount) - int64(back.Leg.InputAmount),
+				})
+				break
+			}
+		}
+	}
+	return sandwiches
+}
+
+// sandwichProfit is the back-run output minus the front-run input for the
+// matched amount min(front out, back in) of the traded token.
+func sandwichProfit(front, back *TxInfo) int64 {
+	matched := min(front.OutputAmount, back.InputAmount)
+	if matched == 0 {
+		return 0
+	}
+	share := func(amount, of uint64) *big.Int {
+		v := new(big.Int).Mul(new(big.Int).SetUint64(amount), new(big.Int).SetUint64(matched))
+		return v.Quo(v, new(big.Int).SetUint64(of))
+	}
+	profit := new(big.Int).Sub(share(back.OutputAmount, back.InputAmount), share(front.InputAmount, front.OutputAmount))
+	return profit.Int64()
+}
+
+// victimLoss undoes the front-run on the reserves the victim traded
+// against. The reserves of a leg are the pool balances after its
+// transaction, the fee of the pool is taken from the victim's own price:
+// with x0, y0 the reserves before the victim, the effective input is
+// out*x0/(y0-out) and without the front-run the victim would have received
+// y*out*x0/(x*(y0-out)+out*x0) from reserves x = x0-frontIn, y = y0+frontOut.
+func victimLoss(front, victim *TxInfo) uint64 {
+	if victim.PoolInAmount == nil || victim.PoolOutAmount == nil || !front.PoolIn.Equals(victim.PoolIn) {
+		return 0
+	}
+	in := new(big.Int).SetUint64(victim.NetInputAmount())
+	out := new(big.Int).SetUint64(victim.OutputAmount)
+	x0 := new(big.Int).Sub(victim.PoolInAmount, in)
+	y0 := new(big.Int).Add(victim.PoolOutAmount, out)
+	x := new(big.Int).Sub(x0, new(big.Int).SetUint64(front.NetInputAmount()))
+	y := new(big.Int).Add(y0, new(big.Int).SetUint64(front.OutputAmount))
+	remaining := new(big.Int).Sub(y0, out)
+	if x0.Sign() <= 0 || x.Sign() <= 0 || remaining.Sign() <= 0 {
+		return 0
+	}
+
+	effective := new(big.Int).Mul(out, x0)
+	num := new(big.Int).Mul(y, effective)
+	den := new(big.Int).Add(new(big.Int).Mul(x, remaining), effective)
+	fair := num.Quo(num, den)
+	if loss := fair.Sub(fair, out); loss.Sign() > 0 && loss.IsUint64() {
+		return loss.Uint64()
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+func sandwichAccount(label string) solana.PublicKey {
+	return solana.PublicKeyFromBytes(sha256Sum("sandwich/" + label))
+}
+
+// buildPoolSwap swaps USDC for the synthetic token on a shared Raydium CPMM
+// pool, or back when buy is false. The vault balances are the reserves
+// before and after the transaction.
+func buildPoolSwap(t testing.TB, name string, trader solana.PublicKey, buy bool, in, out uint64, usdcReserves, tokenReserves [2]uint64) *txBuilder {
+	b := newTxBuilder(t, name)
+	delete(b.index, b.signer)
+	b.signer, b.keys[0], b.index[trader] = trader, trader, 0
+
+	pool, authority := sandwichAccount("pool"), sandwichAccount("authority")
+	vaultUSDC, vaultToken := sandwichAccount("vault-usdc"), sandwichAccount("vault-token")
+	userUSDC, userToken := sandwichAccount(trader.String()+"/usdc"), sandwichAccount(trader.String()+"/token")
+	userIn, userOut, vaultIn, vaultOut, mintIn, mintOut := userUSDC, userToken, vaultUSDC, vaultToken, syntheticUSDC, syntheticMint
+	if !buy {
+		userIn, userOut, vaultIn, vaultOut, mintIn, mintOut = userToken, userUSDC, vaultToken, vaultUSDC, syntheticMint, syntheticUSDC
+	}
+
+	ix := b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		trader, authority, sandwichAccount("amm-config"), pool, userIn, userOut, vaultIn, vaultOut,
+		solana.TokenProgramID, solana.TokenProgramID, mintIn, mintOut, sandwichAccount("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(ix, 2, userIn, vaultIn, trader, in)
+	b.addTransfer(ix, 2, vaultOut, userOut, authority, out)
+
+	b.tokenAccount(userIn, mintIn, trader, 6, in, 0)
+	b.tokenAccount(userOut, mintOut, trader, 6, 0, out)
+	b.tokenAccount(vaultUSDC, syntheticUSDC, authority, 6, usdcReserves[0], usdcReserves[1])
+	b.tokenAccount(vaultToken, syntheticMint, authority, 6, tokenReserves[0], tokenReserves[1])
+	return b
+}
+
+func TestDetectSandwiches(t *testing.T) {
+	attacker, victim, bystander := sandwichAccount("attacker"), sandwichAccount("victim"), sandwichAccount("bystander")
+	txs := []*txBuilder{
+		buildPoolSwap(t, "front-run", attacker, true, 100_000_000, 90_909_090_909,
+			[2]uint64{1_000_000_000, 1_100_000_000}, [2]uint64{1_000_000_000_000, 909_090_909_091}),
+		buildPoolSwap(t, "victim", victim, true, 50_000_000, 39_525_691_699,
+			[2]uint64{1_100_000_000, 1_150_000_000}, [2]uint64{909_090_909_091, 869_565_217_392}),
+		buildPoolSwap(t, "back-run", attacker, false, 90_909_090_909, 108_847_736,
+			[2]uint64{1_150_000_000, 1_041_152_264}, [2]uint64{869_565_217_392, 960_474_308_301}),
+		buildPoolSwap(t, "bystander", bystander, true, 10_000_000, 9_143_000_000,
+			[2]uint64{1_041_152_264, 1_051_152_264}, [2]uint64{960_474_308_301, 951_331_308_301}),
+	}
+
+	var swaps []solanaswapgo.BlockSwap
+	for i, b := range txs {
+		parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+		require.NoError(t, err)
+		legs, err := parser.ParseTransaction()
+		require.NoError(t, err)
+		swaps = append(swaps, parser.BlockSwaps(legs, 300_000_000, i)...)
+	}
+	require.Len(t, swaps, 4)
+
+	sandwiches := solanaswapgo.DetectSandwiches(swaps)
+	require.Len(t, sandwiches, 1)
+	s := sandwiches[0]
+	require.Equal(t, sandwichAccount("pool"), s.Pool)
+	require.Equal(t, attacker, s.Attacker)
+	require.Equal(t, 0, s.FrontRun.TxIndex)
+	require.Equal(t, 2, s.BackRun.TxIndex)
+	require.Equal(t, syntheticUSDC, s.ProfitMint)
+	require.Equal(t, int64(8_847_736), s.Profit)
+	require.Zero(t, s.Unmatched)
+	require.Len(t, s.Victims, 1)
+	require.Equal(t, victim, s.Victims[0].Swap.Signer)
+	require.Equal(t, uint64(8_093_355_919), s.Victims[0].Loss)
+
+	// without a victim in between there is no sandwich
+	require.Empty(t, solanaswapgo.DetectSandwiches([]solanaswapgo.BlockSwap{swaps[0], swaps[2], swaps[3]}))
+
+	// unknown owners do not make the victim a trader of the attacker
+	for _, swap := range swaps {
+		swap.Leg.Owner = solana.PublicKey{}
+	}
+	sandwiches = solanaswapgo.DetectSandwiches(swaps)
+	require.Len(t, sandwiches, 1)
+	require.Len(t, sandwiches[0].Victims, 1)
+	require.Equal(t, victim, sandwiches[0].Victims[0].Swap.Signer)
+}
+
+func TestDetectSandwiches_PartialBackRun(t *testing.T) {
+	attacker, victim := sandwichAccount("attacker"), sandwichAccount("victim")
+	txs := []*txBuilder{
+		buildPoolSwap(t, "front-run", attacker, true, 100_000_000, 90_909_090_909,
+			[2]uint64{1_000_000_000, 1_100_000_000}, [2]uint64{1_000_000_000_000, 909_090_909_091}),
+		buildPoolSwap(t, "victim", victim, true, 50_000_000, 39_525_691_699,
+			[2]uint64{1_100_000_000, 1_150_000_000}, [2]uint64{909_090_909_091, 869_565_217_392}),
+		// the back-run only sells half of what the front-run bought
+		buildPoolSwap(t, "back-run", attacker, false, 45_454_545_454, 55_000_000,
+			[2]uint64{1_150_000_000, 1_095_000_000}, [2]uint64{869_565_217_392, 915_019_762_846}),
+	}
+
+	var swaps []solanaswapgo.BlockSwap
+	for i, b := range txs {
+		parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+		require.NoError(t, err)
+		legs, err := parser.ParseTransaction()
+		require.NoError(t, err)
+		swaps = append(swaps, parser.BlockSwaps(legs, 300_000_000, i)...)
+	}
+
+	sandwiches := solanaswapgo.DetectSandwiches(swaps)
+	require.Len(t, sandwiches, 1)
+	// 55 USDC for the half the front-run bought with 49.999999 USDC, not
+	// 55 - 100 USDC
+	require.Equal(t, int64(5_000_001), sandwiches[0].Profit)
+	require.Equal(t, int64(45_454_545_455), sandwiches[0].Unmatched)
+}
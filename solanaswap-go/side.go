@@ -0,0 +1,62 @@
+package solanaswapgo
+
+import (
+	"github.com/gagliardetto/solana-go"
+)
+
+// DefaultQuoteAssets is the quote asset priority of NewParserConfig: SOL
+// and WSOL, which share NATIVE_SOL_MINT_PROGRAM_ID, then USDC, then USDT.
+var DefaultQuoteAssets = []solana.PublicKey{NATIVE_SOL_MINT_PROGRAM_ID, USDC_MINT, USDT_MINT}
+
+// pair is a swap seen from the market of its two mints.
+type pair struct {
+	side        string
+	base, quote solana.PublicKey
+	baseAmount  uint64
+	quoteAmount uint64
+}
+
+// quoteRank is the priority of mint as a quote asset, lower first.
+func (p *Parser) quoteRank(mint solana.PublicKey) int {
+	for i, quote := range p.config.quoteAssets {
+		if mint.Equals(quote) {
+			return i
+		}
+	}
+	return len(p.config.quoteAssets)
+}
+
+// pair orders the mints of a swap by the quote asset priority. Spending the
+// quote asset buys the base asset, receiving it sells. When neither or both
+// mints rank the same the input is taken as the quote.
+func (p *Parser) pair(inMint solana.PublicKey, inAmount uint64, outMint solana.PublicKey, outAmount uint64) pair {
+	if inMint.IsZero() || outMint.IsZero() {
+		return pair{}
+	}
+	if p.quoteRank(outMint) < p.quoteRank(inMint) {
+		return pair{side: TxSwapSideSell, base: inMint, quote: outMint, baseAmount: inAmount, quoteAmount: outAmount}
+	}
+	return pair{side: TxSwapSideBuy, base: outMint, quote: inMint, baseAmount: outAmount, quoteAmount: inAmount}
+}
+
+// setSide sets the side, base and quote of a swap leg.
+func (p *Parser) setSide(tx *TxInfo) {
+	if tx.Type != TxTypeSwap && tx.Type != "" {
+		return
+	}
+	pair := p.pair(tx.InputMint, tx.InputAmount, tx.OutputMint, tx.OutputAmount)
+	tx.Side = pair.side
+	tx.BaseMint, tx.QuoteMint = pair.base, pair.quote
+	tx.BaseAmount, tx.QuoteAmount = pair.baseAmount, pair.quoteAmount
+}
+
+// enrich adds to a summarized swap what is derived from its amounts: the
+// side, the price and the USD values.
+func (p *Parser) enrich(s *SwapInfo) {
+	pair := p.pair(s.TokenInMint, s.TokenInAmount, s.TokenOutMint, s.TokenOutAmount)
+	s.Side = pair.side
+	s.BaseMint, s.QuoteMint = pair.base, pair.quote
+	s.BaseAmount, s.QuoteAmount = pair.baseAmount, pair.quoteAmount
+	s.setPrice()
+	p.valueSwap(s)
+}
@@ -0,0 +1,53 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSide(t *testing.T) {
+	parser, err := solanaswapgo.NewParserWithConfig(buildRoundTrip(t).result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 2)
+	buy, sell := legs[0].Tx, legs[1].Tx
+	require.Equal(t, solanaswapgo.TxSwapSideBuy, buy.Side)
+	require.Equal(t, syntheticMint, buy.BaseMint)
+	require.Equal(t, syntheticUSDC, buy.QuoteMint)
+	require.Equal(t, uint64(61_234_567_890), buy.BaseAmount)
+	require.Equal(t, uint64(25_000_000), buy.QuoteAmount)
+	require.Equal(t, solanaswapgo.TxSwapSideSell, sell.Side)
+	require.Equal(t, syntheticMint, sell.BaseMint)
+	require.Equal(t, uint64(24_900_000), sell.QuoteAmount)
+
+	swaps, err := parser.ProcessAllSwaps(legs)
+	require.NoError(t, err)
+	require.Len(t, swaps, 2)
+	require.Equal(t, solanaswapgo.TxSwapSideBuy, swaps[0].Side)
+	require.Equal(t, solanaswapgo.TxSwapSideSell, swaps[1].Side)
+	require.Equal(t, syntheticUSDC, swaps[1].QuoteMint)
+
+	// SOL outranks USDC by default, selling SOL buys USDC
+	legs, err = parseWithConfig(t, buildSOLUSDCSwap(t), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Equal(t, solanaswapgo.TxSwapSideBuy, legs[0].Tx.Side)
+	require.Equal(t, syntheticUSDC, legs[0].Tx.BaseMint)
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, legs[0].Tx.QuoteMint)
+
+	parser, err = solanaswapgo.NewParserWithConfig(buildSOLUSDCSwap(t).result(), solanaswapgo.NewParserConfig(
+		solanaswapgo.WithQuoteAssets(solanaswapgo.USDC_MINT, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID),
+	))
+	require.NoError(t, err)
+	legs, err = parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Equal(t, solanaswapgo.TxSwapSideSell, legs[0].Tx.Side)
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, legs[0].Tx.BaseMint)
+	require.Equal(t, uint64(2_000_000_000), legs[0].Tx.BaseAmount)
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, solanaswapgo.TxSwapSideSell, info.Side)
+	require.Equal(t, uint64(300_000_000), info.QuoteAmount)
+}
@@ -0,0 +1,209 @@
+package solanaswapgo
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// TxStatus is the outcome of a transaction on chain.
+type TxStatus string
+
+const (
+	TxStatusSuccess TxStatus = "success"
+	TxStatusFailed  TxStatus = "failed"
+)
+
+// Status returns whether the transaction succeeded.
+func (p *Parser) Status() TxStatus {
+	if p.txMeta.Err != nil {
+		return TxStatusFailed
+	}
+	return TxStatusSuccess
+}
+
+// TxError returns the error of a failed transaction as reported by the
+// node, e.g. {"InstructionError": [2, {"Custom": 6001}]}, nil when it
+// succeeded.
+func (p *Parser) TxError() interface{} {
+	return p.txMeta.Err
+}
+
+// intendedSwaps decodes the swaps a failed transaction meant to make from
+// the AMM instructions it called, see WithFailedSwapIntents.
+func (p *Parser) intendedSwaps() []SwapData {
+	var swaps []SwapData
+	for i, outer := range p.txInfo.Message.Instructions {
+		router := p.allAccountKeys[outer.ProgramIDIndex]
+		if tx := p.intendedSwap(router, outer); tx != nil {
+			tx.Router = router
+			tx.setPosition(i, -1)
+			swaps = append(swaps, SwapData{Type: SwapType(tx.Protocol), Tx: tx, outer: i})
+		}
+		// inner instructions are only recorded up to the failure
+		for n, inner := range p.getInnerInstructions(i) {
+			if tx := p.intendedSwap(p.allAccountKeys[inner.ProgramIDIndex], inner); tx != nil {
+				tx.Router = router
+				tx.setPosition(i, n)
+				swaps = append(swaps, SwapData{Type: SwapType(tx.Protocol), Tx: tx, outer: i})
+			}
+		}
+	}
+	return swaps
+}
+
+// intendedSwap reads the pool of a swap instruction from its AMM descriptor
+// and the amounts from its arguments, laid out as its AMMSwapArgs say. An
+// exact input swap gives InputAmount and MinOutputAmount, an exact output
+// one OutputAmount and MaxInputAmount.
+func (p *Parser) intendedSwap(progID solana.PublicKey, instruction solana.CompiledInstruction) *TxInfo {
+	descriptor, ok := p.config.ammRegistry.Lookup(progID)
+	if !ok {
+		return nil
+	}
+	layout, err := descriptor.match(instruction.Data, len(instruction.Accounts), p.config.kinds)
+	if err != nil || layout.Type != TxTypeSwap {
+		return nil
+	}
+	var args AMMSwapArgs
+	if layout.Args != nil {
+		args = *layout.Args
+	}
+	data := instruction.Data[descriptor.DiscriminatorLen:]
+	if args.Offset < 0 || len(data) < args.Offset+16 {
+		return nil
+	}
+	amount := binary.LittleEndian.Uint64(data[args.Offset:])
+	limit := binary.LittleEndian.Uint64(data[args.Offset+8:])
+	if args.LimitFirst {
+		amount, limit = limit, amount
+	}
+
+	position := func(idx int) (int, bool) {
+		if idx < 0 {
+			idx += len(instruction.Accounts)
+		}
+		return idx, idx >= 0 && idx < len(instruction.Accounts)
+	}
+	pool, okPool := position(layout.Accounts.Pool)
+	in, okIn := p.intentVault(descriptor, instruction, layout.Accounts.PoolIn)
+	out, okOut := p.intentVault(descriptor, instruction, layout.Accounts.PoolOut)
+	if !okPool || !okIn || !okOut {
+		return nil
+	}
+
+	owner := p.owner()
+	if args.Input != nil {
+		idx, ok := position(*args.Input)
+		if !ok {
+			return nil
+		}
+		mint, ok := p.intentMint(instruction.Accounts[idx], owner)
+		if !ok {
+			return nil
+		}
+		if mint.Equals(out.mint) {
+			in, out = out, in
+		}
+	} else {
+		for _, idx := range instruction.Accounts {
+			balance, ok := p.preBalance[idx]
+			if !ok || balance.Owner == nil || !balance.Owner.Equals(owner) {
+				continue
+			}
+			if balance.Mint.Equals(out.mint) {
+				in, out = out, in
+			}
+			if balance.Mint.Equals(in.mint) {
+				break
+			}
+		}
+	}
+
+	tx := &TxInfo{
+		Type:               TxTypeSwap,
+		Amm:                progID,
+		InputMint:          in.mint,
+		InputMintDecimals:  in.decimals,
+		OutputMint:         out.mint,
+		OutputMintDecimals: out.decimals,
+		Pool:               p.allAccountKeys[instruction.Accounts[pool]],
+		PoolIn:             p.allAccountKeys[in.index],
+		PoolOut:            p.allAccountKeys[out.index],
+		PoolInAmount:       in.reserve,
+		PoolOutAmount:      out.reserve,
+		Owner:              owner,
+		Protocol:           descriptor.Protocol,
+		Method:             MethodIntended,
+	}
+	if args.ExactOut {
+		tx.OutputAmount, tx.MaxInputAmount = amount, limit
+	} else {
+		tx.InputAmount, tx.MinOutputAmount = amount, limit
+	}
+	for _, v := range []intentVault{in, out} {
+		if v.lamports {
+			if tx.lamportVaults == nil {
+				tx.lamportVaults = make(map[solana.PublicKey]uint64)
+			}
+			tx.lamportVaults[p.allAccountKeys[v.index]] = v.space
+		}
+	}
+	return tx
+}
+
+// intentVault is a pool vault of an intended swap as it was before the
+// transaction.
+type intentVault struct {
+	index    uint16
+	mint     solana.PublicKey
+	decimals uint8
+	reserve  *big.Int
+	lamports bool
+	space    uint64
+}
+
+// intentVault reads the vault at position pos of instruction from its token
+// balance, or from its lamports when the descriptor marks it as a lamport
+// vault.
+func (p *Parser) intentVault(descriptor *AMMDescriptor, instruction solana.CompiledInstruction, pos int) (intentVault, bool) {
+	if pos < 0 {
+		pos += len(instruction.Accounts)
+	}
+	if pos < 0 || pos >= len(instruction.Accounts) {
+		return intentVault{}, false
+	}
+	idx := instruction.Accounts[pos]
+	if balance, ok := p.preBalance[idx]; ok && balance.UiTokenAmount != nil {
+		reserve, _ := new(big.Int).SetString(balance.UiTokenAmount.Amount, 10)
+		return intentVault{index: idx, mint: balance.Mint, decimals: balance.UiTokenAmount.Decimals, reserve: reserve}, true
+	}
+	space, ok := descriptor.lamportVault(pos, len(instruction.Accounts))
+	if !ok || int(idx) >= len(p.txMeta.PreBalances) {
+		return intentVault{}, false
+	}
+	return intentVault{
+		index:    idx,
+		mint:     NATIVE_SOL_MINT_PROGRAM_ID,
+		decimals: 9,
+		reserve:  new(big.Int).SetUint64(lamportReserve(p.txMeta.PreBalances[idx], space)),
+		lamports: true,
+		space:    space,
+	}, true
+}
+
+// intentMint is the mint the trader pays with from the account at idx:
+// that of its token account, or native SOL when it is the trader itself.
+func (p *Parser) intentMint(idx uint16, owner solana.PublicKey) (solana.PublicKey, bool) {
+	if balance, ok := p.preBalance[idx]; ok {
+		return balance.Mint, true
+	}
+	if balance, ok := p.postBalance[idx]; ok {
+		return balance.Mint, true
+	}
+	if p.allAccountKeys[idx].Equals(owner) {
+		return NATIVE_SOL_MINT_PROGRAM_ID, true
+	}
+	return solana.PublicKey{}, false
+}
@@ -0,0 +1,162 @@
+package solanaswapgo_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+// buildFailedSnipe is a Raydium CPMM buy that reverted on its slippage
+// check: nothing moved and no inner instruction was recorded.
+func buildFailedSnipe(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "failed-snipe")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+	authority := b.account("authority")
+
+	data := append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...)
+	binary.LittleEndian.PutUint64(data[8:], 25_000_000)
+	binary.LittleEndian.PutUint64(data[16:], 61_000_000_000)
+	// the vaults are listed output first, the direction comes from the
+	// token accounts of the trader
+	b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), b.account("pool"), userIn, userOut, vaultOut, vaultIn,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, data)
+
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 100_000_000, 100_000_000)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 0)
+	b.tokenAccount(vaultIn, syntheticUSDC, authority, 6, 5_000_000_000, 5_000_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, authority, 6, 12_000_000_000_000, 12_000_000_000_000)
+	b.lamports(b.signer, 2_000_000_000, 1_999_995_000)
+	b.meta.Err = map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 6005}}}
+	return b
+}
+
+func TestFailedTransaction(t *testing.T) {
+	b := buildFailedSnipe(t)
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Equal(t, solanaswapgo.TxStatusFailed, parser.Status())
+	require.NotNil(t, parser.TxError())
+	legs, diags, err := parser.ParseTransactionWithDiagnostics()
+	require.NoError(t, err)
+	require.Empty(t, legs)
+	require.Equal(t, solanaswapgo.TxStatusFailed, diags.Status)
+
+	parser, err = solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig(solanaswapgo.WithFailedSwapIntents(true)))
+	require.NoError(t, err)
+	legs, err = parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	tx := legs[0].Tx
+	require.Equal(t, solanaswapgo.MethodIntended, tx.Method)
+	require.Equal(t, solanaswapgo.TxStatusFailed, tx.Status)
+	require.Equal(t, syntheticUSDC, tx.InputMint)
+	require.Equal(t, uint64(25_000_000), tx.InputAmount)
+	require.Equal(t, syntheticMint, tx.OutputMint)
+	require.Zero(t, tx.OutputAmount)
+	require.Equal(t, uint64(61_000_000_000), tx.MinOutputAmount)
+	require.Nil(t, tx.ExecutionPrice)
+	require.Equal(t, b.account("pool"), tx.Pool)
+	require.Equal(t, b.account("vault-usdc"), tx.PoolIn)
+	require.Equal(t, "Raydium", tx.Protocol)
+
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, solanaswapgo.TxStatusFailed, info.Status)
+	require.Empty(t, info.Discrepancies)
+
+	legs, err = parseWithConfig(t, buildRaydiumCPMMSwap(t), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Equal(t, solanaswapgo.TxStatusSuccess, legs[0].Tx.Status)
+}
+
+// buildFailedPumpSwapBuy is a PumpSwap buy of base_amount_out for at most
+// max_quote_amount_in that reverted, its base token account comes first.
+func buildFailedPumpSwapBuy(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "failed-pumpswap-buy")
+	pool := b.account("pool")
+	userBase, userQuote := b.account("user-base"), b.account("user-quote")
+	poolBase, poolQuote := b.account("pool-base"), b.account("pool-quote")
+
+	data := append(solanaswapgo.PumpFunAMMBuyDiscriminator[:], make([]byte, 16)...)
+	binary.LittleEndian.PutUint64(data[8:], 2_500_000_000_000)
+	binary.LittleEndian.PutUint64(data[16:], 1_050_000_000)
+	b.addOuter(solanaswapgo.PUMPFUN_AMM_PROGRAM_ID, []solana.PublicKey{
+		pool, b.signer, b.account("global-config"), syntheticMint, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID,
+		userBase, userQuote, poolBase, poolQuote,
+	}, data)
+
+	b.tokenAccount(userBase, syntheticMint, b.signer, 6, 0, 0)
+	b.tokenAccount(userQuote, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, b.signer, 9, 1_100_000_000, 1_100_000_000)
+	b.tokenAccount(poolBase, syntheticMint, pool, 6, 202_500_000_000_000, 202_500_000_000_000)
+	b.tokenAccount(poolQuote, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, pool, 9, 79_000_000_000, 79_000_000_000)
+	b.lamports(b.signer, 5_000_000_000, 4_999_995_000)
+	b.meta.Err = map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 6004}}}
+	return b
+}
+
+// buildFailedPumpFunBuy is a Pump.fun bonding curve buy of amount tokens
+// for at most max_sol_cost that reverted. The curve holds its SOL as
+// lamports and the token account of the buyer was never created.
+func buildFailedPumpFunBuy(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "failed-pumpfun-buy")
+	curve, curveToken := b.account("bonding-curve"), b.account("associated-bonding-curve")
+
+	data := append(solanaswapgo.PumpFunAMMBuyDiscriminator[:], make([]byte, 16)...)
+	binary.LittleEndian.PutUint64(data[8:], 3_000_000_000_000)
+	binary.LittleEndian.PutUint64(data[16:], 1_050_000_000)
+	b.addOuter(solanaswapgo.PUMP_FUN_PROGRAM_ID, []solana.PublicKey{
+		b.account("global"), b.account("fee-recipient"), syntheticMint, curve, curveToken,
+		b.account("associated-user"), b.signer, solana.SystemProgramID, solana.TokenProgramID,
+	}, data)
+
+	b.tokenAccount(curveToken, syntheticMint, curve, 6, 800_000_000_000_000, 800_000_000_000_000)
+	// 30 SOL above the rent-exempt minimum of the 81 bytes of the curve
+	b.lamports(curve, 30_001_454_640, 30_001_454_640)
+	b.lamports(b.signer, 2_000_000_000, 1_999_995_000)
+	b.meta.Err = map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 6002}}}
+	return b
+}
+
+func TestFailedSwapIntents_ExactOut(t *testing.T) {
+	tests := []struct {
+		name        string
+		build       func(testing.TB) *txBuilder
+		protocol    string
+		poolIn      string
+		poolInPre   string
+		wantOut     uint64
+		wantMaxIn   uint64
+		wantPoolOut string
+	}{
+		{"pumpswap", buildFailedPumpSwapBuy, "PumpFun.AMM", "pool-quote", "79000000000", 2_500_000_000_000, 1_050_000_000, "pool-base"},
+		{"pumpfun bonding curve", buildFailedPumpFunBuy, "PumpFun.AMM", "bonding-curve", "30000000000", 3_000_000_000_000, 1_050_000_000, "associated-bonding-curve"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.build(t)
+			legs, err := parseWithConfig(t, b, solanaswapgo.NewParserConfig(solanaswapgo.WithFailedSwapIntents(true)))
+			require.NoError(t, err)
+			require.Len(t, legs, 1)
+			tx := legs[0].Tx
+			require.Equal(t, solanaswapgo.MethodIntended, tx.Method)
+			require.Equal(t, tt.protocol, tx.Protocol)
+			require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, tx.InputMint)
+			require.Equal(t, uint8(9), tx.InputMintDecimals)
+			require.Zero(t, tx.InputAmount)
+			require.Equal(t, tt.wantMaxIn, tx.MaxInputAmount)
+			require.Equal(t, syntheticMint, tx.OutputMint)
+			require.Equal(t, tt.wantOut, tx.OutputAmount)
+			require.Zero(t, tx.MinOutputAmount)
+			require.Equal(t, b.account(tt.poolIn), tx.PoolIn)
+			require.Equal(t, b.account(tt.wantPoolOut), tx.PoolOut)
+			require.Equal(t, tt.poolInPre, tx.PoolInPreAmount.String())
+			require.Nil(t, tx.ExecutionPrice)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package solanaswapgo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// swapGroup is the key of an independent swap: the legs one trader made in
+// one outer instruction. Legs summarized from their first leg only, such as
+// Pump.fun and Moonshot trades, are keyed by their own position in leg.
+type swapGroup struct {
+	trader solana.PublicKey
+	outer  int
+	leg    int
+}
+
+// legTrader returns who the leg was made for. Pump.fun events name their
+// user, the other legs belong to their Owner. The owner of the transaction
+// is reported as the signer ProcessSwapData names, so both entry points
+// agree on it.
+func (p *Parser) legTrader(swap SwapData) solana.PublicKey {
+	trader := p.owner()
+	if event, ok := swap.Data.(*PumpfunTradeEvent); ok && !event.User.IsZero() {
+		trader = event.User
+	} else if swap.Tx != nil && !swap.Tx.Owner.IsZero() {
+		trader = swap.Tx.Owner
+	}
+	if trader.Equals(p.owner()) {
+		return p.signer()
+	}
+	return trader
+}
+
+// soloLeg tells the legs processSwapData summarizes alone from the ones it
+// folds together.
+func soloLeg(swap SwapData) bool {
+	switch swap.Data.(type) {
+	case *PumpfunTradeEvent, *MoonshotTradeInstructionWithMint:
+		return true
+	}
+	return false
+}
+
+// ProcessAllSwaps splits the legs into independent swaps, one per trader
+// and outer instruction and one per Pump.fun or Moonshot trade, and
+// summarizes each like ProcessSwapData. Bundled transactions and bots that
+// buy and sell in one transaction give several swaps. Groups that do not make a swap, such as lone transfers, are left
+// out.
+func (p *Parser) ProcessAllSwaps(swapDatas []SwapData) ([]*SwapInfo, error) {
+	if len(swapDatas) == 0 {
+		return nil, fmt.Errorf("no swap data provided")
+	}
+
+	var order []swapGroup
+	groups := make(map[swapGroup][]SwapData)
+	perTrader := make(map[solana.PublicKey]int)
+	for i, swap := range swapDatas {
+		key := swapGroup{trader: p.legTrader(swap), outer: swap.outer, leg: -1}
+		if soloLeg(swap) {
+			key.leg = i
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+			perTrader[key.trader]++
+		}
+		groups[key] = append(groups[key], swap)
+	}
+
+	var swaps []*SwapInfo
+	costsAttached := false
+	for _, key := range order {
+		swapInfo, _, err := p.processSwapData(groups[key])
+		if errors.Is(err, ErrNoValidSwaps) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("outer instruction %d: %w", key.outer, err)
+		}
+		swapInfo.Signers = []solana.PublicKey{key.trader}
+		p.enrich(swapInfo)
+		if !costsAttached {
+			costsAttached = p.attachCosts(swapInfo)
+		}
+
+		swapInfo.Status = p.Status()
+
+		// the balances of a trader with several swaps only show their sum
+		if perTrader[key.trader] == 1 && swapInfo.Status == TxStatusSuccess {
+			p.validateSwapInfo(swapInfo)
+			if p.config.strict {
+				if err := inconsistency(swapInfo.Discrepancies); err != nil {
+					return nil, fmt.Errorf("outer instruction %d: %w", key.outer, err)
+				}
+			}
+		}
+		swaps = append(swaps, swapInfo)
+	}
+	if len(swaps) == 0 {
+		return nil, ErrNoValidSwaps
+	}
+	return swaps, nil
+}
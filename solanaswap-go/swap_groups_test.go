@@ -0,0 +1,165 @@
+package solanaswapgo_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+// buildRoundTrip buys a token on Raydium CPMM and sells it back to the same
+// pool in the next outer instruction.
+func buildRoundTrip(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "round-trip")
+	pool := b.account("pool")
+	authority := b.account("authority")
+	userUSDC, userToken := b.account("user-usdc"), b.account("user-token")
+	vaultUSDC, vaultToken := b.account("vault-usdc"), b.account("vault-token")
+
+	buy := b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userUSDC, userToken, vaultUSDC, vaultToken,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(buy, 2, userUSDC, vaultUSDC, b.signer, 25_000_000)
+	b.addTransfer(buy, 2, vaultToken, userToken, authority, 61_234_567_890)
+
+	sell := b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userToken, userUSDC, vaultToken, vaultUSDC,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticMint, syntheticUSDC, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+	b.addTransfer(sell, 2, userToken, vaultToken, b.signer, 61_234_567_890)
+	b.addTransfer(sell, 2, vaultUSDC, userUSDC, authority, 24_900_000)
+
+	b.tokenAccount(userUSDC, syntheticUSDC, b.signer, 6, 100_000_000, 99_900_000)
+	b.tokenAccount(userToken, syntheticMint, b.signer, 6, 0, 0)
+	b.tokenAccount(vaultUSDC, syntheticUSDC, authority, 6, 5_000_000_000, 5_000_100_000)
+	b.tokenAccount(vaultToken, syntheticMint, authority, 6, 12_000_000_000_000, 12_000_000_000_000)
+	b.lamports(b.signer, 2_000_000_000, 1_999_995_000)
+	return b
+}
+
+func TestProcessAllSwaps(t *testing.T) {
+	b := buildRoundTrip(t)
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 2)
+
+	swaps, err := parser.ProcessAllSwaps(legs)
+	require.NoError(t, err)
+	require.Len(t, swaps, 2)
+
+	buy, sell := swaps[0], swaps[1]
+	require.Equal(t, []solana.PublicKey{b.signer}, buy.Signers)
+	require.Equal(t, syntheticUSDC, buy.TokenInMint)
+	require.Equal(t, uint64(25_000_000), buy.TokenInAmount)
+	require.Equal(t, syntheticMint, buy.TokenOutMint)
+	require.Equal(t, uint64(61_234_567_890), buy.TokenOutAmount)
+	require.Equal(t, legs[:1], buy.Legs)
+
+	require.Equal(t, syntheticMint, sell.TokenInMint)
+	require.Equal(t, uint64(61_234_567_890), sell.TokenInAmount)
+	require.Equal(t, syntheticUSDC, sell.TokenOutMint)
+	require.Equal(t, uint64(24_900_000), sell.TokenOutAmount)
+	require.Equal(t, legs[1:], sell.Legs)
+
+	// the balances only show the sum of the two
+	require.Zero(t, buy.Confidence)
+	require.Empty(t, sell.Discrepancies)
+
+	// the costs of the transaction are counted once
+	costs := parser.Costs()
+	require.Equal(t, &costs, buy.Costs)
+	require.Nil(t, sell.Costs)
+
+	// and only for a trader who paid them
+	other := b.account("other-trader")
+	legs[0].Tx.Owner = other
+	swaps, err = parser.ProcessAllSwaps(legs)
+	require.NoError(t, err)
+	require.Len(t, swaps, 2)
+	require.Equal(t, []solana.PublicKey{other}, swaps[0].Signers)
+	require.Nil(t, swaps[0].Costs)
+	require.Equal(t, &costs, swaps[1].Costs)
+	legs[0].Tx.Owner = b.signer
+
+	swaps, err = parseAndProcessAll(t, buildRaydiumCPMMSwap(t))
+	require.NoError(t, err)
+	require.Len(t, swaps, 1)
+	require.Equal(t, 1.0, swaps[0].Confidence)
+
+	_, err = parser.ProcessAllSwaps(nil)
+	require.Error(t, err)
+}
+
+func parseAndProcessAll(t *testing.T, b *txBuilder) ([]*solanaswapgo.SwapInfo, error) {
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	return parser.ProcessAllSwaps(legs)
+}
+
+func pumpfunTradeEventData(mint solana.PublicKey, solAmount, tokenAmount uint64, isBuy bool, user solana.PublicKey) []byte {
+	data := append([]byte{}, solanaswapgo.PumpfunTradeEventDiscriminator[:]...)
+	data = append(data, mint[:]...)
+	data = binary.LittleEndian.AppendUint64(data, solAmount)
+	data = binary.LittleEndian.AppendUint64(data, tokenAmount)
+	if isBuy {
+		data = append(data, 1)
+	} else {
+		data = append(data, 0)
+	}
+	data = append(data, user[:]...)
+	return append(data, make([]byte, 24)...)
+}
+
+// buildPumpFunBuySell buys and sells a Pump.fun token in one bot
+// instruction. A relayer pays the fee, the trader signs last.
+func buildPumpFunBuySell(t testing.TB) (*txBuilder, solana.PublicKey) {
+	b := newTxBuilder(t, "pumpfun-buy-sell")
+	trader := b.account("trader")
+	b.key(trader)
+	b.signers = 2
+
+	ix := b.addOuter(solanaswapgo.BLOOM_PROGRAM_ID, []solana.PublicKey{trader}, []byte{1})
+	b.addInner(ix, 2, solanaswapgo.PUMP_FUN_PROGRAM_ID, []solana.PublicKey{trader}, append(solanaswapgo.PumpFunAMMBuyDiscriminator[:], make([]byte, 16)...))
+	b.addInner(ix, 3, solanaswapgo.PUMP_FUN_PROGRAM_ID, nil, pumpfunTradeEventData(syntheticMint, 1_000_000_000, 3_000_000_000_000, true, trader))
+	b.addInner(ix, 2, solanaswapgo.PUMP_FUN_PROGRAM_ID, []solana.PublicKey{trader}, append(solanaswapgo.PumpFunAMMSellDiscriminator[:], make([]byte, 16)...))
+	b.addInner(ix, 3, solanaswapgo.PUMP_FUN_PROGRAM_ID, nil, pumpfunTradeEventData(syntheticMint, 990_000_000, 3_000_000_000_000, false, trader))
+	b.tokenAccount(b.account("trader-token"), syntheticMint, trader, 6, 0, 0)
+	return b, trader
+}
+
+func TestProcessAllSwaps_PerLeg(t *testing.T) {
+	// the buy and the sell share their outer instruction but are two swaps,
+	// both reported for the fee payer like ProcessSwapData does
+	b, _ := buildPumpFunBuySell(t)
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 2)
+
+	swaps, err := parser.ProcessAllSwaps(legs)
+	require.NoError(t, err)
+	require.Len(t, swaps, 2)
+	buy, sell := swaps[0], swaps[1]
+	require.Equal(t, []solana.PublicKey{b.signer}, buy.Signers)
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, buy.TokenInMint)
+	require.Equal(t, uint64(1_000_000_000), buy.TokenInAmount)
+	require.Equal(t, syntheticMint, buy.TokenOutMint)
+	require.Equal(t, legs[:1], buy.Legs)
+	require.Equal(t, []solana.PublicKey{b.signer}, sell.Signers)
+	require.Equal(t, syntheticMint, sell.TokenInMint)
+	require.Equal(t, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, sell.TokenOutMint)
+	require.Equal(t, uint64(990_000_000), sell.TokenOutAmount)
+	require.Equal(t, legs[1:], sell.Legs)
+
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, buy.Signers, info.Signers)
+}
@@ -0,0 +1,270 @@
+package solanaswapgo_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	ag_binary "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// txBuilder assembles a minimal but well-formed transaction + meta pair so
+// the replay harness has cases that do not depend on recorded mainnet data.
+type txBuilder struct {
+	t      testing.TB
+	name   string
+	keys   solana.PublicKeySlice
+	index  map[solana.PublicKey]uint16
+	outer  []solana.CompiledInstruction
+	inner  map[uint16][]rpc.CompiledInstruction
+	meta   rpc.TransactionMeta
+	pre    map[solana.PublicKey]uint64
+	post   map[solana.PublicKey]uint64
+	slot   uint64
+	signer solana.PublicKey
+	// signers is the number of leading keys that sign, signer first.
+	signers uint8
+}
+
+func newTxBuilder(t testing.TB, name string) *txBuilder {
+	b := &txBuilder{
+		t:       t,
+		name:    name,
+		index:   map[solana.PublicKey]uint16{},
+		inner:   map[uint16][]rpc.CompiledInstruction{},
+		pre:     map[solana.PublicKey]uint64{},
+		post:    map[solana.PublicKey]uint64{},
+		slot:    300_000_000,
+		signers: 1,
+	}
+	b.signer = b.account("signer")
+	b.key(b.signer)
+	b.meta.Fee = 5000
+	return b
+}
+
+// account derives a stable pseudo address for a label.
+func (b *txBuilder) account(label string) solana.PublicKey {
+	return solana.PublicKeyFromBytes(sha256Sum(b.name + "/" + label))
+}
+
+func (b *txBuilder) key(pk solana.PublicKey) uint16 {
+	if i, ok := b.index[pk]; ok {
+		return i
+	}
+	b.index[pk] = uint16(len(b.keys))
+	b.keys = append(b.keys, pk)
+	return b.index[pk]
+}
+
+func (b *txBuilder) compile(prog solana.PublicKey, accounts []solana.PublicKey, data []byte) solana.CompiledInstruction {
+	ix := solana.CompiledInstruction{ProgramIDIndex: b.key(prog), Data: data}
+	for _, acc := range accounts {
+		ix.Accounts = append(ix.Accounts, b.key(acc))
+	}
+	return ix
+}
+
+// addOuter appends a top-level instruction and returns its index.
+func (b *txBuilder) addOuter(prog solana.PublicKey, accounts []solana.PublicKey, data []byte) uint16 {
+	b.outer = append(b.outer, b.compile(prog, accounts, data))
+	return uint16(len(b.outer) - 1)
+}
+
+// addInner appends a CPI made while executing outer instruction idx.
+func (b *txBuilder) addInner(idx uint16, stackHeight uint16, prog solana.PublicKey, accounts []solana.PublicKey, data []byte) {
+	ix := b.compile(prog, accounts, data)
+	b.inner[idx] = append(b.inner[idx], rpc.CompiledInstruction{
+		ProgramIDIndex: ix.ProgramIDIndex,
+		Accounts:       ix.Accounts,
+		Data:           ix.Data,
+		StackHeight:    stackHeight,
+	})
+}
+
+// addTransfer appends an SPL Token Transfer CPI.
+func (b *txBuilder) addTransfer(idx uint16, stackHeight uint16, from, to, authority solana.PublicKey, amount uint64) {
+	data := make([]byte, 9)
+	data[0] = 3
+	binary.LittleEndian.PutUint64(data[1:], amount)
+	b.addInner(idx, stackHeight, solana.TokenProgramID, []solana.PublicKey{from, to, authority}, data)
+}
+
+// tokenAccount registers a token account with its pre and post balances.
+func (b *txBuilder) tokenAccount(account, mint, owner solana.PublicKey, decimals uint8, pre, post uint64) {
+	i := b.key(account)
+	balance := func(amount uint64) rpc.TokenBalance {
+		return rpc.TokenBalance{
+			AccountIndex: i,
+			Owner:        &owner,
+			ProgramId:    &solana.TokenProgramID,
+			Mint:         mint,
+			UiTokenAmount: &rpc.UiTokenAmount{
+				Amount:   strconv.FormatUint(amount, 10),
+				Decimals: decimals,
+			},
+		}
+	}
+	b.meta.PreTokenBalances = append(b.meta.PreTokenBalances, balance(pre))
+	b.meta.PostTokenBalances = append(b.meta.PostTokenBalances, balance(post))
+}
+
+// lamports sets the native balance of an account before and after the tx.
+func (b *txBuilder) lamports(account solana.PublicKey, pre, post uint64) {
+	b.key(account)
+	b.pre[account] = pre
+	b.post[account] = post
+}
+
+// result encodes the transaction the same way getTransaction does.
+func (b *txBuilder) result() *rpc.GetTransactionResult {
+	tx := &solana.Transaction{
+		Signatures: []solana.Signature{solana.SignatureFromBytes(append(sha256Sum(b.name), sha256Sum(b.name+"/sig")...))},
+		Message: solana.Message{
+			AccountKeys:     b.keys,
+			Header:          solana.MessageHeader{NumRequiredSignatures: b.signers},
+			RecentBlockhash: solana.HashFromBytes(sha256Sum(b.name + "/blockhash")),
+			Instructions:    b.outer,
+		},
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		b.t.Fatalf("marshal synthetic tx: %s", err)
+	}
+
+	meta := b.meta
+	meta.PreBalances = make([]uint64, len(b.keys))
+	meta.PostBalances = make([]uint64, len(b.keys))
+	for i, k := range b.keys {
+		meta.PreBalances[i] = b.pre[k]
+		meta.PostBalances[i] = b.post[k]
+	}
+	meta.InnerInstructions = []rpc.InnerInstruction{}
+	for i := range b.outer {
+		if inner, ok := b.inner[uint16(i)]; ok {
+			meta.InnerInstructions = append(meta.InnerInstructions, rpc.InnerInstruction{Index: uint16(i), Instructions: inner})
+		}
+	}
+	meta.LoadedAddresses = rpc.LoadedAddresses{Writable: solana.PublicKeySlice{}, ReadOnly: solana.PublicKeySlice{}}
+
+	envelope, err := json.Marshal(map[string]interface{}{
+		"slot":        b.slot,
+		"blockTime":   1_730_000_000,
+		"transaction": []string{base64.StdEncoding.EncodeToString(raw), "base64"},
+		"meta":        meta,
+		"version":     "legacy",
+	})
+	if err != nil {
+		b.t.Fatalf("marshal synthetic result: %s", err)
+	}
+	var result rpc.GetTransactionResult
+	if err := json.Unmarshal(envelope, &result); err != nil {
+		b.t.Fatalf("decode synthetic result: %s", err)
+	}
+	return &result
+}
+
+func (b *txBuilder) signature() string {
+	return solana.SignatureFromBytes(append(sha256Sum(b.name), sha256Sum(b.name+"/sig")...)).String()
+}
+
+func sha256Sum(s string) []byte {
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+func anchorDiscriminator(name string) []byte {
+	return sha256Sum("global:" + name)[:8]
+}
+
+func borsh(t testing.TB, v interface{}) []byte {
+	raw, err := ag_binary.MarshalBorsh(v)
+	if err != nil {
+		t.Fatalf("borsh encode: %s", err)
+	}
+	return raw
+}
+
+var (
+	syntheticMint = solana.MustPublicKeyFromBase58("E95sJahssFKUk6jcWYbyfmjtcCsr4Z226HD9Qbjupump")
+	syntheticUSDC = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+)
+
+// syntheticCase is a hand-built transaction replayed like a recorded fixture.
+type syntheticCase struct {
+	name  string
+	build func(t testing.TB) *txBuilder
+}
+
+var syntheticCases = []syntheticCase{
+	{name: "pumpswap-buy", build: buildPumpSwapBuy},
+	{name: "raydium-cpmm-swap", build: buildRaydiumCPMMSwap},
+}
+
+// buildPumpSwapBuy is a direct PumpSwap buy: SOL in, token out, parsed from
+// the BuyEvent emitted through the self-CPI.
+func buildPumpSwapBuy(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "pumpswap-buy")
+	pool := b.account("pool")
+	userBase, userQuote := b.account("user-base"), b.account("user-quote")
+	poolBase, poolQuote := b.account("pool-base"), b.account("pool-quote")
+
+	ix := b.addOuter(solanaswapgo.PUMPFUN_AMM_PROGRAM_ID, []solana.PublicKey{
+		pool, b.signer, b.account("global-config"), syntheticMint, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID,
+		userBase, userQuote, poolBase, poolQuote,
+	}, append(solanaswapgo.PumpFunAMMBuyDiscriminator[:], make([]byte, 16)...))
+
+	b.addTransfer(ix, 2, userQuote, poolQuote, b.signer, 1_000_000_000)
+	b.addTransfer(ix, 2, poolBase, userBase, pool, 2_500_000_000_000)
+
+	event := borsh(t, solanaswapgo.PumpfunAMMBuyEvent{
+		Timestamp:              1_730_000_000,
+		BaseAmountOut:          2_500_000_000_000,
+		PoolBaseTokenReserves:  200_000_000_000_000,
+		PoolQuoteTokenReserves: 80_000_000_000,
+		QuoteAmountInWithLpFee: 1_000_000_000,
+		UserQuoteAmountIn:      1_002_500_000,
+		Pool:                   pool,
+		User:                   b.signer,
+	})
+	b.addInner(ix, 2, solanaswapgo.PUMPFUN_AMM_PROGRAM_ID, []solana.PublicKey{b.account("event-authority")},
+		append(solanaswapgo.PumpFunAMMBuyEventDiscriminator[:], event...))
+
+	b.tokenAccount(userBase, syntheticMint, b.signer, 6, 0, 2_500_000_000_000)
+	b.tokenAccount(userQuote, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, b.signer, 9, 1_000_000_000, 0)
+	b.tokenAccount(poolBase, syntheticMint, pool, 6, 202_500_000_000_000, 200_000_000_000_000)
+	b.tokenAccount(poolQuote, solanaswapgo.NATIVE_SOL_MINT_PROGRAM_ID, pool, 9, 79_000_000_000, 80_000_000_000)
+	b.lamports(b.signer, 5_000_000_000, 4_999_995_000)
+	return b
+}
+
+// buildRaydiumCPMMSwap is a direct Raydium CPMM swap_base_input parsed from
+// its two token transfers and the post balances of the pool vaults.
+func buildRaydiumCPMMSwap(t testing.TB) *txBuilder {
+	b := newTxBuilder(t, "raydium-cpmm-swap")
+	pool := b.account("pool")
+	authority := b.account("authority")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+
+	ix := b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userIn, userOut, vaultIn, vaultOut,
+		solana.TokenProgramID, solana.TokenProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+
+	b.addTransfer(ix, 2, userIn, vaultIn, b.signer, 25_000_000)
+	b.addTransfer(ix, 2, vaultOut, userOut, authority, 61_234_567_890)
+
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 100_000_000, 75_000_000)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 61_234_567_890)
+	b.tokenAccount(vaultIn, syntheticUSDC, authority, 6, 5_000_000_000, 5_025_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, authority, 6, 12_000_000_000_000, 11_938_765_432_110)
+	b.lamports(b.signer, 2_000_000_000, 1_999_995_000)
+	return b
+}
@@ -0,0 +1,75 @@
+package solanaswapgo
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/franco-bianco/solanaswap-go/solanaswap-go/meteora_damm_v2"
+	"github.com/gagliardetto/solana-go"
+)
+
+// anchorEventTag prefixes the data of the self CPI Anchor programs log
+// their events with.
+var anchorEventTag = [8]byte{228, 69, 165, 46, 81, 203, 154, 29}
+
+// SetBlockContext sets the slot, block time and position in the block of
+// the transaction, txIndex -1 when unknown. Parsers made from a
+// getTransaction result take the slot and block time from it, call this for
+// raw transactions before parsing.
+func (p *Parser) SetBlockContext(slot uint64, blockTime time.Time, txIndex int) {
+	p.slot = slot
+	p.blockTime = blockTime
+	p.txIndex = txIndex
+}
+
+// Slot returns the slot the transaction landed in, 0 when unknown.
+func (p *Parser) Slot() uint64 {
+	return p.slot
+}
+
+// TxIndex returns the position of the transaction in its block, -1 when
+// unknown.
+func (p *Parser) TxIndex() int {
+	return p.txIndex
+}
+
+// BlockTime returns the time of the block of the transaction, zero when
+// unknown.
+func (p *Parser) BlockTime() time.Time {
+	return p.blockTime
+}
+
+// protocolTimestamp returns the first timestamp logged by the AMMs of the
+// legs.
+func protocolTimestamp(swapDatas []SwapData) time.Time {
+	for _, swap := range swapDatas {
+		if event, ok := swap.Data.(*PumpfunTradeEvent); ok && event.Timestamp != 0 {
+			return time.Unix(event.Timestamp, 0)
+		}
+		if swap.Tx != nil && !swap.Tx.ProtocolTimestamp.IsZero() {
+			return swap.Tx.ProtocolTimestamp
+		}
+	}
+	return time.Time{}
+}
+
+// setDAMMv2Timestamp sets the ProtocolTimestamp of a Meteora DAMM v2 leg
+// from the first swap event of its pool among the instructions.
+func (p *Parser) setDAMMv2Timestamp(tx *TxInfo, instructions []solana.CompiledInstruction) {
+	for _, instr := range instructions {
+		if !p.allAccountKeys[instr.ProgramIDIndex].Equals(METEORA_DAMM_V2) ||
+			len(instr.Data) < 16 || !bytes.Equal(instr.Data[:8], anchorEventTag[:]) ||
+			!bytes.Equal(instr.Data[8:16], meteora_damm_v2.Event_EvtSwap[:]) {
+			continue
+		}
+		event, err := meteora_damm_v2.ParseEvent_EvtSwap(instr.Data[8:])
+		if err != nil {
+			p.log.Debug("failed to decode damm v2 swap event", "err", err)
+			continue
+		}
+		if event.Pool.Equals(tx.Pool) {
+			tx.ProtocolTimestamp = time.Unix(int64(event.CurrentTimestamp), 0)
+			return
+		}
+	}
+}
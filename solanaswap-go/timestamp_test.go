@@ -0,0 +1,49 @@
+package solanaswapgo_test
+
+import (
+	"testing"
+	"time"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockTimestamps(t *testing.T) {
+	parser, err := solanaswapgo.NewParserWithConfig(buildRaydiumCPMMSwap(t).result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Equal(t, uint64(300_000_000), parser.Slot())
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Equal(t, uint64(300_000_000), legs[0].Tx.Slot)
+	require.Equal(t, time.Unix(1_730_000_000, 0), legs[0].Tx.BlockTime)
+	require.True(t, legs[0].Tx.ProtocolTimestamp.IsZero())
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, uint64(300_000_000), info.Slot)
+	require.Equal(t, time.Unix(1_730_000_000, 0), info.Timestamp)
+
+	// a raw transaction has no block context until it is given one
+	result := buildPumpSwapBuy(t).result()
+	tx, err := result.Transaction.GetTransaction()
+	require.NoError(t, err)
+	parser, err = solanaswapgo.NewTransactionParserWithConfig(tx, result.Meta, solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err = parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Zero(t, legs[0].Tx.Slot)
+	require.True(t, legs[0].Tx.BlockTime.IsZero())
+	require.Equal(t, time.Unix(1_730_000_000, 0), legs[0].Tx.ProtocolTimestamp)
+	info, _, err = parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.True(t, info.BlockTime.IsZero())
+	require.Equal(t, info.ProtocolTimestamp, info.Timestamp)
+
+	parser.SetBlockContext(300_000_002, time.Unix(1_730_000_400, 0), 7)
+	legs, err = parser.ParseTransaction()
+	require.NoError(t, err)
+	info, _, err = parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, uint64(300_000_002), info.Slot)
+	require.Equal(t, time.Unix(1_730_000_400, 0), info.Timestamp)
+	require.Equal(t, time.Unix(1_730_000_000, 0), info.ProtocolTimestamp)
+}
@@ -0,0 +1,277 @@
+package solanaswapgo
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Token program instructions recognized as transfers. Token-2022 shares the
+// layout of the legacy program and adds TransferCheckedWithFee to the
+// transfer fee extension.
+const (
+	tokenInstructionTransfer                     = 3
+	tokenInstructionTransferChecked              = 12
+	tokenInstructionTransferFeeExtension         = 26
+	transferFeeInstructionTransferCheckedWithFee = 1
+)
+
+func isTokenProgram(progID solana.PublicKey) bool {
+	return progID.Equals(solana.TokenProgramID) || progID.Equals(solana.Token2022ProgramID)
+}
+
+// checkedTransfer is the data of a TransferChecked or TransferCheckedWithFee
+// instruction. Both take the source, mint, destination and authority
+// accounts, transfer hooks append their extra accounts after them.
+type checkedTransfer struct {
+	amount      uint64
+	decimals    uint8
+	hasDecimals bool
+	fee         uint64
+	withFee     bool
+}
+
+func decodeCheckedTransfer(data []byte) (checkedTransfer, bool) {
+	switch {
+	case len(data) >= 9 && data[0] == tokenInstructionTransferChecked:
+		t := checkedTransfer{amount: binary.LittleEndian.Uint64(data[1:9])}
+		if len(data) >= 10 {
+			t.decimals, t.hasDecimals = data[9], true
+		}
+		return t, true
+	case len(data) >= 19 && data[0] == tokenInstructionTransferFeeExtension && data[1] == transferFeeInstructionTransferCheckedWithFee:
+		return checkedTransfer{
+			amount:      binary.LittleEndian.Uint64(data[2:10]),
+			decimals:    data[10],
+			hasDecimals: true,
+			fee:         binary.LittleEndian.Uint64(data[11:19]),
+			withFee:     true,
+		}, true
+	}
+	return checkedTransfer{}, false
+}
+
+// MintExtensions flags the Token-2022 extensions of a mint that change what
+// its amounts mean.
+type MintExtensions uint8
+
+const (
+	// MintExtensionTransferFee withholds a fee from every transfer, the
+	// receiver gets less than the amount sent.
+	MintExtensionTransferFee MintExtensions = 1 << iota
+	// MintExtensionTransferHook calls a program on every transfer.
+	MintExtensionTransferHook
+	// MintExtensionInterestBearing shows UI amounts with accrued interest.
+	MintExtensionInterestBearing
+	// MintExtensionScaledUIAmount shows UI amounts multiplied by a factor.
+	// It is also set when the token balances of the transaction show a UI
+	// amount that is not the raw amount shifted by the decimals and the
+	// MintInfoProvider does not tell which extension causes it.
+	MintExtensionScaledUIAmount
+)
+
+func (e MintExtensions) Has(ext MintExtensions) bool {
+	return e&ext != 0
+}
+
+// TransferFee is the transfer fee configuration of a mint.
+type TransferFee struct {
+	BasisPoints uint16 `json:"basisPoints"`
+	MaximumFee  uint64 `json:"maximumFee"`
+}
+
+// Calculate returns the fee withheld from a transfer of amount, rounded up
+// like the Token-2022 program does.
+func (f TransferFee) Calculate(amount uint64) uint64 {
+	if f.BasisPoints == 0 || amount == 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(amount, uint64(min(f.BasisPoints, maxFeeBasisPoints)))
+	fee, rem := bits.Div64(hi, lo, maxFeeBasisPoints)
+	if rem > 0 {
+		fee++
+	}
+	return min(fee, f.MaximumFee)
+}
+
+const maxFeeBasisPoints = 10_000
+
+// Token-2022 extension types and the layout of the TransferFeeConfig, see
+// spl-token-2022 extension/mod.rs.
+const (
+	mintExtensionsOffset           = tokenAccountSize + 1
+	extensionTransferFeeConfig     = 1
+	extensionInterestBearingConfig = 10
+	extensionTransferHook          = 14
+	extensionScaledUIAmount        = 25
+
+	// authorities and withheld amount, then the older and the newer fee
+	// as epoch, maximum fee and basis points.
+	newerTransferFeeOffset = 32 + 32 + 8 + 18
+	transferFeeConfigSize  = newerTransferFeeOffset + 18
+)
+
+// parseMintExtensions reads the extensions of a Token-2022 mint account.
+// The transfer fee is the newer of the two configured, which applies once
+// its epoch is reached.
+func parseMintExtensions(data []byte) (MintExtensions, *TransferFee) {
+	var (
+		exts MintExtensions
+		fee  *TransferFee
+	)
+	for offset := mintExtensionsOffset; offset+4 <= len(data); {
+		extType := binary.LittleEndian.Uint16(data[offset:])
+		length := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		value := data[offset+4 : min(offset+4+length, len(data))]
+		switch extType {
+		case extensionTransferFeeConfig:
+			exts |= MintExtensionTransferFee
+			if len(value) >= transferFeeConfigSize {
+				fee = &TransferFee{
+					MaximumFee:  binary.LittleEndian.Uint64(value[newerTransferFeeOffset+8:]),
+					BasisPoints: binary.LittleEndian.Uint16(value[newerTransferFeeOffset+16:]),
+				}
+			}
+		case extensionInterestBearingConfig:
+			exts |= MintExtensionInterestBearing
+		case extensionTransferHook:
+			exts |= MintExtensionTransferHook
+		case extensionScaledUIAmount:
+			exts |= MintExtensionScaledUIAmount
+		}
+		offset += 4 + length
+	}
+	return exts, fee
+}
+
+type transferFeeKey struct {
+	mint   solana.PublicKey
+	amount uint64
+}
+
+// extractToken2022Info collects the extensions of the mints moved by the
+// transaction and the fees withheld by their transfers.
+func (p *Parser) extractToken2022Info() {
+	p.mintExtensions = make(map[solana.PublicKey]MintExtensions)
+	p.transferFees = make(map[transferFeeKey]uint64)
+
+	var token2022Mints []solana.PublicKey
+	seen := make(map[solana.PublicKey]bool)
+	addMint := func(mint solana.PublicKey) {
+		if !seen[mint] {
+			seen[mint] = true
+			token2022Mints = append(token2022Mints, mint)
+		}
+	}
+
+	uiScaled := make(map[solana.PublicKey]bool)
+	for _, balances := range [][]rpc.TokenBalance{p.txMeta.PreTokenBalances, p.txMeta.PostTokenBalances} {
+		for _, balance := range balances {
+			if balance.ProgramId == nil || !balance.ProgramId.Equals(solana.Token2022ProgramID) {
+				continue
+			}
+			addMint(balance.Mint)
+			amount := balance.UiTokenAmount
+			if amount != nil && amount.UiAmountString != "" && amount.UiAmountString != rawUIAmount(amount.Amount, amount.Decimals) {
+				uiScaled[balance.Mint] = true
+			}
+		}
+	}
+
+	// hooked reports whether the transfer is followed by a CPI of its own,
+	// which only the transfer hook program makes.
+	visit := func(instr solana.CompiledInstruction, hooked bool) {
+		if !p.allAccountKeys[instr.ProgramIDIndex].Equals(solana.Token2022ProgramID) || len(instr.Accounts) < 3 {
+			return
+		}
+		transfer, ok := decodeCheckedTransfer(instr.Data)
+		if !ok {
+			return
+		}
+		mint := p.allAccountKeys[instr.Accounts[1]]
+		addMint(mint)
+		if transfer.withFee {
+			p.mintExtensions[mint] |= MintExtensionTransferFee
+			p.transferFees[transferFeeKey{mint, transfer.amount}] = transfer.fee
+		}
+		if hooked {
+			p.mintExtensions[mint] |= MintExtensionTransferHook
+		}
+	}
+	for i, instr := range p.txInfo.Message.Instructions {
+		inners := p.getInnerInstructions(i)
+		visit(instr, len(inners) > 0)
+	}
+	for _, innerSet := range p.txMeta.InnerInstructions {
+		for j, instr := range innerSet.Instructions {
+			next := j + 1
+			hooked := instr.StackHeight > 0 && next < len(innerSet.Instructions) && innerSet.Instructions[next].StackHeight > instr.StackHeight
+			visit(p.convertRPCToSolanaInstruction(instr), hooked)
+		}
+	}
+
+	// the balances already give the decimals of most of them, only the
+	// others are looked up
+	for _, mint := range token2022Mints {
+		p.needMint(mint)
+	}
+	p.token2022Mints = token2022Mints
+	p.uiScaledMints = uiScaled
+}
+
+// setMintExtensions adds the extensions the MintInfoProvider knows of to
+// those seen in the transfers of the Token-2022 mints.
+func (p *Parser) setMintExtensions() {
+	for _, mint := range p.token2022Mints {
+		info := p.mintInfos[mint]
+		p.mintExtensions[mint] |= info.Extensions
+		if p.uiScaledMints[mint] && !(!info.Mint.IsZero() && info.Extensions.Has(MintExtensionInterestBearing|MintExtensionScaledUIAmount)) {
+			p.mintExtensions[mint] |= MintExtensionScaledUIAmount
+		}
+	}
+}
+
+// rawUIAmount formats amount with decimals the way the RPC node formats
+// uiAmountString of mints without UI amount extensions.
+func rawUIAmount(amount string, decimals uint8) string {
+	d := int(decimals)
+	if d == 0 {
+		return amount
+	}
+	if len(amount) <= d {
+		amount = strings.Repeat("0", d-len(amount)+1) + amount
+	}
+	s := amount[:len(amount)-d] + "." + amount[len(amount)-d:]
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// transferFee returns the fee withheld when amount of mint is transferred.
+func (p *Parser) transferFee(mint solana.PublicKey, amount uint64) uint64 {
+	if !p.mintExtensions[mint].Has(MintExtensionTransferFee) {
+		return 0
+	}
+	if fee, ok := p.transferFees[transferFeeKey{mint, amount}]; ok {
+		return fee
+	}
+	if info := p.mintInfos[mint]; info.TransferFee != nil {
+		return info.TransferFee.Calculate(amount)
+	}
+	return 0
+}
+
+// setToken2022Info fills the transfer fees and mint extensions of the legs.
+func (p *Parser) setToken2022Info(swaps []SwapData) {
+	for _, swap := range swaps {
+		tx := swap.Tx
+		if tx == nil {
+			continue
+		}
+		tx.InputMintExtensions = p.mintExtensions[tx.InputMint]
+		tx.OutputMintExtensions = p.mintExtensions[tx.OutputMint]
+		tx.InputTransferFee = p.transferFee(tx.InputMint, tx.InputAmount)
+		tx.OutputTransferFee = p.transferFee(tx.OutputMint, tx.OutputAmount)
+	}
+}
@@ -0,0 +1,146 @@
+package solanaswapgo_test
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func transferCheckedData(amount uint64, decimals uint8) []byte {
+	data := make([]byte, 10)
+	data[0] = 12
+	binary.LittleEndian.PutUint64(data[1:], amount)
+	data[9] = decimals
+	return data
+}
+
+func transferCheckedWithFeeData(amount uint64, decimals uint8, fee uint64) []byte {
+	data := make([]byte, 19)
+	data[0], data[1] = 26, 1
+	binary.LittleEndian.PutUint64(data[2:], amount)
+	data[10] = decimals
+	binary.LittleEndian.PutUint64(data[11:], fee)
+	return data
+}
+
+// buildToken2022CPMMSwap swaps USDC for a Token-2022 mint. The input is a
+// plain Token-2022 Transfer, the output a TransferChecked of a transfer hook
+// mint, so withFee only changes how the output is sent.
+func buildToken2022CPMMSwap(t testing.TB, withFee bool) *txBuilder {
+	b := newTxBuilder(t, "token2022-cpmm-swap")
+	pool := b.account("pool")
+	authority := b.account("authority")
+	userIn, userOut := b.account("user-usdc"), b.account("user-token")
+	vaultIn, vaultOut := b.account("vault-usdc"), b.account("vault-token")
+
+	ix := b.addOuter(solanaswapgo.RAYDIUM_CPMM_PROGRAM_ID, []solana.PublicKey{
+		b.signer, authority, b.account("amm-config"), pool, userIn, userOut, vaultIn, vaultOut,
+		solana.Token2022ProgramID, solana.Token2022ProgramID, syntheticUSDC, syntheticMint, b.account("observation"),
+	}, append(anchorDiscriminator("swap_base_input"), make([]byte, 16)...))
+
+	transfer := make([]byte, 9)
+	transfer[0] = 3
+	binary.LittleEndian.PutUint64(transfer[1:], 25_000_000)
+	b.addInner(ix, 2, solana.Token2022ProgramID, []solana.PublicKey{userIn, vaultIn, b.signer}, transfer)
+
+	hook := b.account("hook-program")
+	accounts := []solana.PublicKey{vaultOut, syntheticMint, userOut, authority, b.account("hook-extra-metas"), hook}
+	if withFee {
+		b.addInner(ix, 2, solana.Token2022ProgramID, accounts, transferCheckedWithFeeData(61_000_000, 6, 610_000))
+	} else {
+		b.addInner(ix, 2, solana.Token2022ProgramID, accounts, transferCheckedData(61_000_000, 6))
+	}
+	b.addInner(ix, 3, hook, []solana.PublicKey{vaultOut, syntheticMint, userOut, authority}, sha256Sum("spl-transfer-hook-interface:execute")[:8])
+
+	b.tokenAccount(userIn, syntheticUSDC, b.signer, 6, 100_000_000, 75_000_000)
+	b.tokenAccount(userOut, syntheticMint, b.signer, 6, 0, 60_390_000)
+	b.tokenAccount(vaultIn, syntheticUSDC, authority, 6, 5_000_000_000, 5_025_000_000)
+	b.tokenAccount(vaultOut, syntheticMint, authority, 6, 12_000_000_000, 11_939_000_000)
+	for _, balances := range [][]rpc.TokenBalance{b.meta.PreTokenBalances, b.meta.PostTokenBalances} {
+		for i := range balances {
+			balances[i].ProgramId = &solana.Token2022ProgramID
+		}
+	}
+	b.lamports(b.signer, 2_000_000_000, 1_999_995_000)
+	return b
+}
+
+func TestToken2022TransferCheckedWithFee(t *testing.T) {
+	legs, err := parseWithConfig(t, buildToken2022CPMMSwap(t, true), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+
+	tx := legs[0].Tx
+	require.Equal(t, syntheticUSDC, tx.InputMint)
+	require.Equal(t, uint64(25_000_000), tx.InputAmount)
+	require.Equal(t, uint64(25_000_000), tx.NetInputAmount())
+	require.Equal(t, syntheticMint, tx.OutputMint)
+	require.Equal(t, uint64(61_000_000), tx.OutputAmount)
+	require.Equal(t, uint64(610_000), tx.OutputTransferFee)
+	require.Equal(t, uint64(60_390_000), tx.NetOutputAmount())
+	require.True(t, tx.OutputMintExtensions.Has(solanaswapgo.MintExtensionTransferFee))
+	require.True(t, tx.OutputMintExtensions.Has(solanaswapgo.MintExtensionTransferHook))
+	require.Zero(t, tx.InputMintExtensions)
+}
+
+// token2022MintAccount lays out a Token-2022 mint with a transfer fee config.
+func token2022MintAccount(decimals uint8, basisPoints uint16, maximumFee uint64) []byte {
+	data := make([]byte, 166, 166+4+108)
+	data[44] = decimals
+	data[165] = 1
+	ext := make([]byte, 4+108)
+	binary.LittleEndian.PutUint16(ext[0:], 1)
+	binary.LittleEndian.PutUint16(ext[2:], 108)
+	binary.LittleEndian.PutUint64(ext[4+90+8:], maximumFee)
+	binary.LittleEndian.PutUint16(ext[4+90+16:], basisPoints)
+	return append(data, ext...)
+}
+
+func TestToken2022TransferFeeFromMintInfo(t *testing.T) {
+	fake := &fakeAccounts{accounts: map[solana.PublicKey]*rpc.Account{
+		syntheticMint: tokenProgramAccount(solana.Token2022ProgramID, token2022MintAccount(6, 100, 5_000_000)),
+	}}
+	infos, err := solanaswapgo.NewRPCMintInfo(fake).MintInfo(context.Background(), []solana.PublicKey{syntheticMint})
+	require.NoError(t, err)
+	require.Equal(t, &solanaswapgo.TransferFee{BasisPoints: 100, MaximumFee: 5_000_000}, infos[syntheticMint].TransferFee)
+
+	// the balances give the decimals of the mint, it is not looked up
+	fake.calls = 0
+	legs, err := parseWithConfig(t, buildToken2022CPMMSwap(t, false), solanaswapgo.NewParserConfig(solanaswapgo.WithMintInfoProvider(solanaswapgo.NewRPCMintInfo(fake))))
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Zero(t, fake.calls)
+	require.Zero(t, legs[0].Tx.OutputTransferFee)
+
+	legs, err = parseWithConfig(t, buildToken2022CPMMSwap(t, false), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Zero(t, legs[0].Tx.OutputTransferFee)
+	require.Equal(t, solanaswapgo.MintExtensionTransferHook, legs[0].Tx.OutputMintExtensions)
+}
+
+func TestTransferFeeCalculate(t *testing.T) {
+	fee := solanaswapgo.TransferFee{BasisPoints: 250, MaximumFee: 1_000}
+	require.Equal(t, uint64(0), fee.Calculate(0))
+	require.Equal(t, uint64(1), fee.Calculate(1))
+	require.Equal(t, uint64(25), fee.Calculate(1_000))
+	require.Equal(t, uint64(1_000), fee.Calculate(^uint64(0)))
+}
+
+func TestScaledUIAmountFlag(t *testing.T) {
+	b := buildToken2022CPMMSwap(t, true)
+	uiAmounts := []string{"75", "120.78", "5025", "23878"} // token balances at a multiplier of 2
+	for i := range b.meta.PostTokenBalances {
+		b.meta.PostTokenBalances[i].UiTokenAmount.UiAmountString = uiAmounts[i]
+	}
+	legs, err := parseWithConfig(t, b, solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.True(t, legs[0].Tx.OutputMintExtensions.Has(solanaswapgo.MintExtensionScaledUIAmount))
+	require.False(t, legs[0].Tx.InputMintExtensions.Has(solanaswapgo.MintExtensionScaledUIAmount))
+}
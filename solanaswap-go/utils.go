@@ -1,6 +1,8 @@
 package solanaswapgo
 
 import (
+	"fmt"
+
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
@@ -12,3 +14,75 @@ func (p *Parser) convertRPCToSolanaInstruction(rpcInst rpc.CompiledInstruction)
 		Data:           rpcInst.Data,
 	}
 }
+
+// owner returns the last signer of the transaction, or the zero key when the
+// message declares none.
+func (p *Parser) owner() solana.PublicKey {
+	if signer := p.txInfo.Message.Signers().Last(); signer != nil {
+		return *signer
+	}
+	return solana.PublicKey{}
+}
+
+// signer returns the trader a SwapInfo names in its Signers: the fee
+// payer, or the user of a Jupiter DCA order.
+func (p *Parser) signer() solana.PublicKey {
+	if p.containsDCAProgram() && len(p.allAccountKeys) > 2 {
+		return p.allAccountKeys[2]
+	}
+	return p.allAccountKeys[0]
+}
+
+// mintFromString parses a mint kept as a string in TransferData and
+// TransferCheck. Placeholders such as "Unknown" give the zero key.
+func mintFromString(mint string) solana.PublicKey {
+	pk, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return solana.PublicKey{}
+	}
+	return pk
+}
+
+// validateAccountIndexes makes sure every account index of the transaction
+// and its meta points into allAccountKeys, so the parsers can index it
+// directly.
+func (p *Parser) validateAccountIndexes() error {
+	keys := len(p.allAccountKeys)
+	if keys == 0 {
+		return fmt.Errorf("%w: transaction has no account keys", ErrAccountIndexOutOfRange)
+	}
+	check := func(programIDIndex uint16, accounts []uint16) error {
+		if int(programIDIndex) >= keys {
+			return fmt.Errorf("%w: program index %d/%d", ErrAccountIndexOutOfRange, programIDIndex, keys)
+		}
+		for _, acc := range accounts {
+			if int(acc) >= keys {
+				return fmt.Errorf("%w: account index %d/%d", ErrAccountIndexOutOfRange, acc, keys)
+			}
+		}
+		return nil
+	}
+	for _, instr := range p.txInfo.Message.Instructions {
+		if err := check(instr.ProgramIDIndex, instr.Accounts); err != nil {
+			return err
+		}
+	}
+	for _, innerSet := range p.txMeta.InnerInstructions {
+		if int(innerSet.Index) >= len(p.txInfo.Message.Instructions) {
+			return fmt.Errorf("%w: inner instructions of missing instruction %d", ErrAccountIndexOutOfRange, innerSet.Index)
+		}
+		for _, instr := range innerSet.Instructions {
+			if err := check(instr.ProgramIDIndex, instr.Accounts); err != nil {
+				return err
+			}
+		}
+	}
+	for _, balances := range [][]rpc.TokenBalance{p.txMeta.PreTokenBalances, p.txMeta.PostTokenBalances} {
+		for _, balance := range balances {
+			if int(balance.AccountIndex) >= keys {
+				return fmt.Errorf("%w: token balance index %d/%d", ErrAccountIndexOutOfRange, balance.AccountIndex, keys)
+			}
+		}
+	}
+	return nil
+}
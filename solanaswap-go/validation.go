@@ -0,0 +1,157 @@
+package solanaswapgo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Discrepancy is a parsed amount that does not match the change of the
+// balance it moved.
+type Discrepancy struct {
+	// Leg is the index of the leg in the parsed swaps, -1 for the SwapInfo.
+	Leg int
+	// Field names the parsed amount, e.g. "InputAmount" or "TokenOutAmount".
+	Field string
+	// Account is the trader or the pool vault whose balance was compared.
+	Account  solana.PublicKey
+	Mint     solana.PublicKey
+	Expected int64
+	Observed int64
+}
+
+func (d Discrepancy) Error() string {
+	return fmt.Sprintf("leg %d %s: %s of %s changed by %d, expected %d", d.Leg, d.Field, d.Account, d.Mint, d.Observed, d.Expected)
+}
+
+// validation collects the outcome of the checks of one result.
+type validation struct {
+	checks        int
+	discrepancies []Discrepancy
+}
+
+func (v *validation) check(d Discrepancy, matches ...int64) {
+	v.checks++
+	for _, m := range matches {
+		if d.Observed == m {
+			return
+		}
+	}
+	v.discrepancies = append(v.discrepancies, d)
+}
+
+// confidence is the share of the checks that agreed with the balances, 0
+// when none could be made.
+func (v *validation) confidence() float64 {
+	if v.checks == 0 {
+		return 0
+	}
+	return float64(v.checks-len(v.discrepancies)) / float64(v.checks)
+}
+
+// accountDelta returns the change of the balance of mint held by the account
+// at index: its token balance, or its lamports when it holds native SOL.
+func (p *Parser) accountDelta(index uint16, mint solana.PublicKey) (int64, bool) {
+	pre, hasPre := p.preBalance[index]
+	post, hasPost := p.postBalance[index]
+	if !hasPre && !hasPost {
+		if !mint.Equals(NATIVE_SOL_MINT_PROGRAM_ID) || int(index) >= len(p.txMeta.PreBalances) || int(index) >= len(p.txMeta.PostBalances) {
+			return 0, false
+		}
+		return int64(p.txMeta.PostBalances[index]) - int64(p.txMeta.PreBalances[index]), true
+	}
+	var delta int64
+	if hasPost {
+		if !post.Mint.Equals(mint) {
+			return 0, false
+		}
+		v, _ := strconv.ParseInt(post.UiTokenAmount.Amount, 10, 64)
+		delta += v
+	}
+	if hasPre {
+		if !pre.Mint.Equals(mint) {
+			return 0, false
+		}
+		v, _ := strconv.ParseInt(pre.UiTokenAmount.Amount, 10, 64)
+		delta -= v
+	}
+	return delta, true
+}
+
+// traderDelta returns the change of mint held by the trader, native SOL and
+// WSOL counted together without fee and rent.
+func (p *Parser) traderDelta(trader, mint solana.PublicKey) int64 {
+	delta, _ := p.balanceDelta(trader, mint)
+	return delta.Amount
+}
+
+// validateLegs compares the legs with the changes of the pool vaults they
+// name and, for a single leg, with the changes of the trader.
+func (p *Parser) validateLegs(swaps []SwapData) {
+	vaultUses := make(map[solana.PublicKey]int)
+	for _, swap := range swaps {
+		if swap.Tx != nil {
+			vaultUses[swap.Tx.PoolIn]++
+			vaultUses[swap.Tx.PoolOut]++
+		}
+	}
+
+	for i, swap := range swaps {
+		tx := swap.Tx
+		if tx == nil || tx.Type != TxTypeSwap {
+			continue
+		}
+		var v validation
+		vault := func(field string, account, mint solana.PublicKey, expected int64) {
+			if account.IsZero() || vaultUses[account] > 1 {
+				return
+			}
+			index, ok := p.accountIndex(account)
+			if !ok {
+				return
+			}
+			if observed, ok := p.accountDelta(uint16(index), mint); ok {
+				v.check(Discrepancy{Leg: i, Field: field, Account: account, Mint: mint, Expected: expected, Observed: observed}, expected)
+			}
+		}
+		vault("PoolIn", tx.PoolIn, tx.InputMint, int64(tx.NetInputAmount()))
+		vault("PoolOut", tx.PoolOut, tx.OutputMint, -int64(tx.OutputAmount))
+
+		if len(swaps) == 1 && !tx.InputMint.Equals(tx.OutputMint) {
+			input, output := -int64(tx.InputAmount), int64(tx.NetOutputAmount())
+			v.check(Discrepancy{Leg: i, Field: "InputAmount", Account: tx.Owner, Mint: tx.InputMint, Expected: input, Observed: p.traderDelta(tx.Owner, tx.InputMint)}, input)
+			v.check(Discrepancy{Leg: i, Field: "OutputAmount", Account: tx.Owner, Mint: tx.OutputMint, Expected: output, Observed: p.traderDelta(tx.Owner, tx.OutputMint)}, output)
+		}
+		tx.Confidence, tx.Discrepancies = v.confidence(), v.discrepancies
+	}
+}
+
+// validateSwapInfo compares the amounts of the swap with the changes of the
+// signer. The output may have lost a transfer fee on the way.
+func (p *Parser) validateSwapInfo(info *SwapInfo) {
+	if len(info.Signers) == 0 || info.TokenInMint.Equals(info.TokenOutMint) {
+		return
+	}
+	var v validation
+	trader := info.Signers[0]
+	input := -int64(info.TokenInAmount)
+	v.check(Discrepancy{Leg: -1, Field: "TokenInAmount", Account: trader, Mint: info.TokenInMint, Expected: input, Observed: p.traderDelta(trader, info.TokenInMint)}, input)
+	output := int64(info.TokenOutAmount)
+	net := output - int64(p.transferFee(info.TokenOutMint, info.TokenOutAmount))
+	v.check(Discrepancy{Leg: -1, Field: "TokenOutAmount", Account: trader, Mint: info.TokenOutMint, Expected: output, Observed: p.traderDelta(trader, info.TokenOutMint)}, output, net)
+	info.Confidence, info.Discrepancies = v.confidence(), v.discrepancies
+}
+
+// inconsistency joins the discrepancies for strict parsers.
+func inconsistency(discrepancies []Discrepancy) error {
+	if len(discrepancies) == 0 {
+		return nil
+	}
+	errs := make([]error, len(discrepancies))
+	for i, d := range discrepancies {
+		errs[i] = d
+	}
+	return fmt.Errorf("%w: %w", ErrInconsistentParse, errors.Join(errs...))
+}
@@ -0,0 +1,58 @@
+package solanaswapgo_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	solanaswapgo "github.com/franco-bianco/solanaswap-go/solanaswap-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidation(t *testing.T) {
+	b := buildRaydiumCPMMSwap(t)
+	parser, err := solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err := parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	require.Equal(t, 1.0, legs[0].Tx.Confidence)
+	require.Empty(t, legs[0].Tx.Discrepancies)
+
+	info, _, err := parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, info.Confidence)
+	require.Empty(t, info.Discrepancies)
+
+	// the transfer out of the vault claims one unit more than it moved
+	b = buildRaydiumCPMMSwap(t)
+	binary.LittleEndian.PutUint64(b.inner[0][1].Data[1:], 61_234_567_891)
+	vaultOut := b.account("vault-token")
+
+	parser, err = solanaswapgo.NewParserWithConfig(b.result(), solanaswapgo.NewParserConfig())
+	require.NoError(t, err)
+	legs, err = parser.ParseTransaction()
+	require.NoError(t, err)
+	require.Len(t, legs, 1)
+	tx := legs[0].Tx
+	require.Equal(t, 0.5, tx.Confidence)
+	require.Len(t, tx.Discrepancies, 2)
+	require.Equal(t, solanaswapgo.Discrepancy{
+		Leg:      0,
+		Field:    "PoolOut",
+		Account:  vaultOut,
+		Mint:     syntheticMint,
+		Expected: -61_234_567_891,
+		Observed: -61_234_567_890,
+	}, tx.Discrepancies[0])
+	require.Equal(t, b.signer, tx.Discrepancies[1].Account)
+
+	info, _, err = parser.ProcessSwapData(legs)
+	require.NoError(t, err)
+	require.Equal(t, 0.5, info.Confidence)
+	require.Len(t, info.Discrepancies, 1)
+
+	strict := solanaswapgo.NewParserConfig(solanaswapgo.WithStrict(true))
+	legs, err = parseWithConfig(t, b, strict)
+	require.ErrorIs(t, err, solanaswapgo.ErrInconsistentParse)
+	require.Empty(t, legs)
+}